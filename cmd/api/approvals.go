@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"kite-api/internal/data"
+	"kite-api/internal/validator"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// canReadUnapproved reports whether the current user may see words and translations
+// that have not been approved yet.
+func (app *application) canReadUnapproved(r *http.Request) (bool, error) {
+	user := app.contextGetUser(r)
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions.Include("approvals:read"), nil
+}
+
+// readStatuses works out which review states a listing may return.
+// Readers without approvals:read only ever get approved entries,
+// reviewers get whatever they ask for in the status query string, or everything.
+func (app *application) readStatuses(r *http.Request, qs url.Values, v *validator.Validator) ([]string, error) {
+	canRead, err := app.canReadUnapproved(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canRead {
+		return []string{data.StatusApproved}, nil
+	}
+
+	statuses := app.readCSV(qs, "status", []string{})
+	for _, status := range statuses {
+		v.Check(validator.IsPermittedValue(status, data.Statuses), "status", "must be one of: draft, submitted, approved, rejected")
+	}
+
+	return statuses, nil
+}
+
+func (app *application) submitWordHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	word, err := app.models.Words.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.canModifyWord(w, r, word) {
+		return
+	}
+
+	approval := &data.Approval{
+		ActorID:  app.contextGetUser(r).ID,
+		ToStatus: data.StatusSubmitted,
+	}
+
+	app.changeWordStatus(w, r, word, approval)
+}
+
+func (app *application) reviewWordHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	word, err := app.models.Words.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	approval, ok := app.readReview(w, r)
+	if !ok {
+		return
+	}
+
+	app.changeWordStatus(w, r, word, approval)
+}
+
+func (app *application) changeWordStatus(w http.ResponseWriter, r *http.Request, word *data.Word, approval *data.Approval) {
+	v := validator.New()
+
+	if v.Check(data.CanTransition(word.Status, approval.ToStatus), "status", "cannot move a "+word.Status+" word to "+approval.ToStatus); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Approvals.ChangeWordStatus(word, approval)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateWord):
+			v.AddError("text", "an approved word with this text already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"word": word, "approval": approval}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listWordApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	approvals, err := app.models.Approvals.GetAllForWord(word.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"approvals": approvals}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) submitTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	translation, ok := app.readTranslation(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canModifyTranslation(w, r, translation) {
+		return
+	}
+
+	approval := &data.Approval{
+		ActorID:  app.contextGetUser(r).ID,
+		ToStatus: data.StatusSubmitted,
+	}
+
+	app.changeTranslationStatus(w, r, translation, approval)
+}
+
+func (app *application) reviewTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	translation, ok := app.readTranslation(w, r)
+	if !ok {
+		return
+	}
+
+	approval, ok := app.readReview(w, r)
+	if !ok {
+		return
+	}
+
+	app.changeTranslationStatus(w, r, translation, approval)
+}
+
+func (app *application) changeTranslationStatus(w http.ResponseWriter, r *http.Request, translation *data.Translation, approval *data.Approval) {
+	v := validator.New()
+
+	if v.Check(data.CanTransition(translation.Status, approval.ToStatus), "status", "cannot move a "+translation.Status+" translation to "+approval.ToStatus); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err := app.models.Approvals.ChangeTranslationStatus(translation, approval)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation, "approval": approval}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listTranslationApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	translation, ok := app.readTranslation(w, r)
+	if !ok {
+		return
+	}
+
+	approvals, err := app.models.Approvals.GetAllForTranslation(translation.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"approvals": approvals}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readReview decodes a reviewer's decision from the request body.
+// It sends the error response itself and returns false when the body is unusable.
+func (app *application) readReview(w http.ResponseWriter, r *http.Request) (*data.Approval, bool) {
+	var input struct {
+		Decision string `json:"decision"`
+		Comment  string `json:"comment"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return nil, false
+	}
+
+	v := validator.New()
+
+	v.Check(validator.IsPermittedValue(input.Decision, []string{data.StatusApproved, data.StatusRejected}), "decision", "must be one of: approved, rejected")
+	v.Check(input.Decision != data.StatusRejected || strings.TrimSpace(input.Comment) != "", "comment", "must explain why the entry was rejected")
+	v.Check(len(input.Comment) <= 1000, "comment", "must be 1000 or less characters")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return nil, false
+	}
+
+	approval := &data.Approval{
+		ActorID:  app.contextGetUser(r).ID,
+		ToStatus: input.Decision,
+		Comment:  input.Comment,
+	}
+
+	return approval, true
+}
+
+// readTranslation looks up the translation addressed by the :id and :translation_id path parameters.
+// Translations of a word the user may not see, or one in the trash, are hidden along with it.
+// It sends the error response itself and returns false when there is no such translation.
+func (app *application) readTranslation(w http.ResponseWriter, r *http.Request) (*data.Translation, bool) {
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	id, err := app.readNamedIDParam(r, "translation_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	translation, err := app.models.Translations.Get(word.ID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return translation, true
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"kite-api/internal/data"
+	"kite-api/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportTimeout is how long an export may take to stream
+const exportTimeout = 10 * time.Minute
+
+// exportedWord is the shape of a word in an NDJSON export. The import reads it back,
+// ignoring the id and status.
+type exportedWord struct {
+	ID           int64    `json:"id"`
+	Text         string   `json:"text"`
+	Difficulty   string   `json:"difficulty"`
+	RelatedWords []string `json:"related_words"`
+	Status       string   `json:"status"`
+}
+
+func newExportedWord(word *data.Word) exportedWord {
+	return exportedWord{
+		ID:           word.ID,
+		Text:         word.TextValue,
+		Difficulty:   word.Difficulty,
+		RelatedWords: word.RelatedWords,
+		Status:       word.Status,
+	}
+}
+
+// exportHeader names the columns of a CSV or TSV export, which exportRecord fills in
+var exportHeader = []string{"id", "text", "difficulty", "related_words", "status"}
+
+func exportRecord(word *data.Word) []string {
+	return []string{
+		strconv.FormatInt(word.ID, 10),
+		word.TextValue,
+		word.Difficulty,
+		strings.Join(word.RelatedWords, ";"),
+		word.Status,
+	}
+}
+
+// exportWordsHandler streams every word matching the same search terms as listWordsHandler,
+// without paging, as CSV, TSV or newline-delimited JSON (?format=csv|tsv|ndjson).
+// In CSV and TSV exports related words are joined with semicolons, as the import expects.
+func (app *application) exportWordsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	search, err := app.readWordSearch(r, qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	format := app.readString(qs, "format", "csv")
+	v.Check(validator.IsPermittedValue(format, []string{"csv", "tsv", "ndjson"}), "format", "must be one of: csv, tsv, ndjson")
+
+	filters := data.Filters{Sort: app.readWordSort(qs), SortSafeList: wordSortSafeList}
+	v.Check(validator.IsPermittedValue(filters.Sort, filters.SortSafeList), "sort", "invalid sort value")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The whole dictionary takes longer to send than the server's usual write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	var (
+		write       func(*data.Word) error
+		flush       func() error
+		contentType string
+	)
+
+	switch format {
+	case "ndjson":
+		contentType = "application/x-ndjson"
+		enc := json.NewEncoder(w)
+
+		write = func(word *data.Word) error {
+			return enc.Encode(newExportedWord(word))
+		}
+		flush = func() error { return nil }
+	default:
+		writer := csv.NewWriter(w)
+		contentType = "text/csv; charset=utf-8"
+
+		if format == "tsv" {
+			writer.Comma = '\t'
+			contentType = "text/tab-separated-values; charset=utf-8"
+		}
+
+		// This only reaches the buffer, so an error response can still replace it
+		_ = writer.Write(exportHeader)
+
+		write = func(word *data.Word) error {
+			return writer.Write(exportRecord(word))
+		}
+		flush = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="words.`+format+`"`)
+
+	written := 0
+
+	err = app.models.Words.Export(search, filters, func(word *data.Word) error {
+		if word.RelatedWords == nil {
+			word.RelatedWords = []string{}
+		}
+
+		if err := write(word); err != nil {
+			return err
+		}
+
+		// Push the data out every so often rather than buffering the whole export
+		written++
+		if written%500 == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+			_ = rc.Flush()
+		}
+
+		return nil
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	if err != nil {
+		if written == 0 {
+			w.Header().Del("Content-Disposition")
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// Once the body has started there is no way to send an error response. Cutting
+		// the connection tells the client the export is incomplete, where a clean
+		// end to the body would pass it off as the whole dictionary.
+		app.logError(r, err)
+		panic(http.ErrAbortHandler)
+	}
+}
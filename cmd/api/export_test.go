@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"kite-api/internal/data"
+	"testing"
+)
+
+var exportTestWords = []*data.Word{
+	{ID: 1, TextValue: "kite", Difficulty: "easy", RelatedWords: []string{"wind", "sky"}, Status: data.StatusApproved},
+	{ID: 2, TextValue: "caf\u00e9, \"quoted\"", Difficulty: "hard", RelatedWords: []string{}, Status: data.StatusDraft},
+	{ID: 3, TextValue: "\u1000\u1060\u102f", Difficulty: "medium", RelatedWords: []string{"\u1001"}, Status: data.StatusApproved},
+}
+
+// wantImported is what the import should read back from an export of exportTestWords,
+// whose first record is on the given line
+func wantImported(firstLine int) []wantRecord {
+	want := make([]wantRecord, 0, len(exportTestWords))
+	for i, word := range exportTestWords {
+		want = append(want, wantRecord{
+			line:       firstLine + i,
+			text:       word.TextValue,
+			difficulty: word.Difficulty,
+			related:    word.RelatedWords,
+		})
+	}
+	return want
+}
+
+func TestExportImportRoundTripNDJSON(t *testing.T) {
+	var body bytes.Buffer
+
+	enc := json.NewEncoder(&body)
+	for _, word := range exportTestWords {
+		if err := enc.Encode(newExportedWord(word)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkImportRecords(t, ndjsonImportReader(&body), wantImported(1))
+}
+
+func TestExportImportRoundTripCSV(t *testing.T) {
+	var body bytes.Buffer
+
+	writer := csv.NewWriter(&body)
+	_ = writer.Write(exportHeader)
+	for _, word := range exportTestWords {
+		_ = writer.Write(exportRecord(word))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		t.Fatal(err)
+	}
+
+	next, err := csvImportReader(&body)
+	if err != nil {
+		t.Fatalf("csvImportReader() error = %v", err)
+	}
+
+	checkImportRecords(t, next, wantImported(2))
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"kite-api/internal/data"
+	"kite-api/internal/validator"
+	"net/http"
+)
+
+func (app *application) createFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	// Feedback always has to point at an existing word the user can see
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	wordID := word.ID
+
+	var input struct {
+		Category string `json:"category"`
+		Message  string `json:"message"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	feedback := &data.Feedback{
+		WordID:   wordID,
+		UserID:   app.contextGetUser(r).ID,
+		Category: input.Category,
+		Message:  input.Message,
+		Status:   data.FeedbackOpen,
+	}
+
+	v := validator.New()
+
+	if data.ValidateFeedback(v, feedback); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Feedbacks.Insert(feedback)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/feedback/%d", feedback.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"feedback": feedback}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	feedback, err := app.models.Feedbacks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"feedback": feedback}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateFeedbackHandler lets a moderator move feedback through the triage states
+func (app *application) updateFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	feedback, err := app.models.Feedbacks.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	v := validator.New()
+
+	v.Check(validator.IsPermittedValue(input.Status, data.FeedbackStatuses), "status", "must be one of: open, acknowledged, resolved")
+	if v.Valid() {
+		v.Check(data.CanTransitionFeedback(feedback.Status, input.Status), "status", "cannot move "+feedback.Status+" feedback to "+input.Status)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feedback.Status = input.Status
+
+	err = app.models.Feedbacks.Update(feedback)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"feedback": feedback}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listFeedbackHandler serves the moderators' triage queue, oldest feedback first by default
+func (app *application) listFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	app.listFeedback(w, r, 0)
+}
+
+// listWordFeedbackHandler serves the triage queue narrowed down to a single word
+func (app *application) listWordFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	wordID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.listFeedback(w, r, wordID)
+}
+
+func (app *application) listFeedback(w http.ResponseWriter, r *http.Request, wordID int64) {
+	var input struct {
+		Status   string
+		Category string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Status = app.readString(qs, "status", "")
+	input.Category = app.readString(qs, "category", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "created_at")
+
+	input.Filters.SortSafeList = []string{"id", "created_at", "status", "category", "-id", "-created_at", "-status", "-category"}
+
+	if input.Status != "" {
+		v.Check(validator.IsPermittedValue(input.Status, data.FeedbackStatuses), "status", "must be one of: open, acknowledged, resolved")
+	}
+
+	if input.Category != "" {
+		v.Check(validator.IsPermittedValue(input.Category, data.FeedbackCategories), "category", "invalid category value")
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feedbacks, metadata, err := app.models.Feedbacks.GetAll(wordID, input.Status, input.Category, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"feedback": feedbacks, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
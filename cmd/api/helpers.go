@@ -15,9 +15,14 @@ import (
 
 // Helper method to extract ID param and check if it's valid
 func (app *application) readIDParam(r *http.Request) (int64, error) {
+	return app.readNamedIDParam(r, "id")
+}
+
+// readNamedIDParam extracts an ID from the named path parameter, e.g. :translation_id
+func (app *application) readNamedIDParam(r *http.Request, name string) (int64, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
-	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	id, err := strconv.ParseInt(params.ByName(name), 10, 64)
 
 	if err != nil || id < 1 {
 		return 0, errors.New("invalid ID")
@@ -26,8 +31,79 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// versionETag builds the ETag of a resource from its optimistic locking version.
+// The version changes on every write, so it identifies the representation.
+func versionETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// etagListMatches reports whether a comma separated If-Match or If-None-Match
+// header value names the ETag. Weak validators only count when weak is set,
+// as If-None-Match allows and If-Match does not.
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch enforces the If-Match header on a request that changes a resource
+// at the given version. Clients that don't send the header are not held to it.
+// It sends a 412 Precondition Failed response itself and returns false on a mismatch.
+func (app *application) checkIfMatch(w http.ResponseWriter, r *http.Request, version int64) bool {
+	header := r.Header.Get("If-Match")
+
+	if header != "" && !etagListMatches(header, versionETag(version), false) {
+		app.preconditionFailedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+// notModified handles If-None-Match on a read of a resource at the given version.
+// It always sets the ETag header, and when the client's copy is current it sends
+// a 304 Not Modified response itself and returns true.
+func (app *application) notModified(w http.ResponseWriter, r *http.Request, version int64) bool {
+	etag := versionETag(version)
+	w.Header().Set("ETag", etag)
+
+	header := r.Header.Get("If-None-Match")
+
+	if header != "" && etagListMatches(header, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
 type envelope map[string]any
 
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header.
+// It returns false when the header is missing or is not a bearer token.
+func (app *application) bearerToken(r *http.Request) (string, bool) {
+	headerParts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return "", false
+	}
+
+	return headerParts[1], true
+}
+
 // Helper method to convert a GO object to a json
 func (app *application) writeJSON(w http.ResponseWriter, status int, data envelope, headers http.Header) error {
 
@@ -130,7 +206,15 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 		return defaultValue
 	}
 
-	return strings.Split(csv, ", ")
+	// Accept "a,b" as well as "a, b", and ignore empty entries
+	values := []string{}
+	for _, value := range strings.Split(csv, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
 }
 
 // readInt finds a key and return its values, if exists,
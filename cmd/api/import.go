@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kite-api/internal/data"
+	"kite-api/internal/validator"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// importMaxBytes caps an import body, well above the 1MB readJSON allows
+	importMaxBytes = 64 * 1024 * 1024
+	// importBatchSize is the number of words inserted per transaction
+	importBatchSize = 500
+	// importTimeout is how long an import may take to upload and process
+	importTimeout = 5 * time.Minute
+)
+
+// Outcomes of a single imported row
+const (
+	importCreated = "created"
+	importSkipped = "skipped"
+	importFailed  = "failed"
+)
+
+type importRow struct {
+	Line   int               `json:"line"` // where the row starts in the body, counting from 1`
+	Status string            `json:"status"`
+	ID     int64             `json:"id,omitempty"`
+	Text   string            `json:"text,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type importReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Created int          `json:"created"`
+	Skipped int          `json:"skipped"`
+	Failed  int          `json:"failed"`
+	Rows    []*importRow `json:"rows"`
+}
+
+func (report *importReport) add(row *importRow) {
+	switch row.Status {
+	case importCreated:
+		report.Created++
+	case importSkipped:
+		report.Skipped++
+	case importFailed:
+		report.Failed++
+	}
+
+	report.Rows = append(report.Rows, row)
+}
+
+// importRecord is a word read from the body, or the reason it couldn't be read
+type importRecord struct {
+	Text         string   `json:"text"`
+	Difficulty   string   `json:"difficulty"`
+	RelatedWords []string `json:"related_words"`
+
+	// An export carries the id and status too. Imported words are always new drafts,
+	// so both are accepted, to let an export be imported again, and then ignored.
+	ID     json.RawMessage `json:"id"`
+	Status json.RawMessage `json:"status"`
+
+	line int
+	err  error
+}
+
+// importWordsHandler creates words in bulk from a CSV (text/csv) or newline-delimited
+// JSON (application/x-ndjson) body. Rows are validated one by one and inserted in batches;
+// rows whose text is already in the dictionary are skipped. With ?dry_run=true nothing is
+// saved, but the report shows what would have happened.
+//
+// CSV bodies need a header row naming the text, difficulty and related_words columns.
+// Related words are separated by semicolons within their column. Other columns, like the
+// id and status of an export, are ignored.
+func (app *application) importWordsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	v := validator.New()
+
+	dryRun, err := strconv.ParseBool(app.readString(qs, "dry_run", "false"))
+	if v.Check(err == nil, "dry_run", "must be true or false"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var next func() (*importRecord, error)
+
+	// Big imports take longer than the server's usual timeouts allow
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(importTimeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(importTimeout))
+
+	body := http.MaxBytesReader(w, r.Body, importMaxBytes)
+
+	switch mediaType {
+	case "text/csv":
+		next, err = csvImportReader(body)
+		if err != nil {
+			app.badRequestResponse(w, r, err.Error())
+			return
+		}
+	case "application/x-ndjson", "application/ndjson":
+		next = ndjsonImportReader(body)
+	default:
+		app.errorResponse(w, r, http.StatusUnsupportedMediaType, "Content-Type must be text/csv or application/x-ndjson")
+		return
+	}
+
+	report := &importReport{DryRun: dryRun, Rows: []*importRow{}}
+	owner := app.contextGetUser(r).ID
+
+	// seen catches duplicates within the body itself, which matters for dry runs
+	// where earlier batches are never actually written
+	seen := make(map[string]bool)
+
+	var (
+		batch     []*data.Word
+		batchRows []*importRow
+	)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		created, err := app.models.Words.InsertBatch(batch, dryRun)
+		if err != nil {
+			return err
+		}
+
+		for i, row := range batchRows {
+			if created[i] {
+				row.Status = importCreated
+				if !dryRun {
+					row.ID = batch[i].ID
+				}
+			} else {
+				row.Status = importSkipped
+			}
+			report.add(row)
+		}
+
+		batch, batchRows = batch[:0], batchRows[:0]
+		return nil
+	}
+
+	for {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			// The body itself is unreadable, so stop here and tell the client how far we got
+			if flushErr := flush(); flushErr != nil {
+				app.serverErrorResponse(w, r, flushErr)
+				return
+			}
+
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				err = fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+			}
+
+			app.errorResponse(w, r, http.StatusBadRequest, envelope{"message": err.Error(), "report": report})
+			return
+		}
+
+		row := &importRow{Line: record.line, Text: record.Text}
+
+		if record.err != nil {
+			row.Status = importFailed
+			row.Errors = map[string]string{"row": record.err.Error()}
+			report.add(row)
+			continue
+		}
+
+		word := &data.Word{
+			TextValue:    record.Text,
+			Difficulty:   record.Difficulty,
+			RelatedWords: record.RelatedWords,
+			UserId:       owner,
+			Status:       data.StatusDraft,
+		}
+
+		if word.RelatedWords == nil {
+			word.RelatedWords = []string{}
+		}
+
+		rowValidator := validator.New()
+
+		if data.ValidateWord(rowValidator, word); !rowValidator.Valid() {
+			row.Status = importFailed
+			row.Errors = rowValidator.Errors
+			report.add(row)
+			continue
+		}
+
+		key := data.SearchKey(word.TextValue)
+		if seen[key] {
+			row.Status = importSkipped
+			report.add(row)
+			continue
+		}
+		seen[key] = true
+
+		batch = append(batch, word)
+		batchRows = append(batchRows, row)
+
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if report.Created > 0 && !dryRun {
+		status = http.StatusCreated
+	}
+
+	err = app.writeJSON(w, status, envelope{"import": report}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// csvImportReader returns a function that reads one word per CSV record.
+// The columns are picked out by the header row, so their order doesn't matter.
+// Records are numbered by the line they start on, since a quoted field can span lines.
+func csvImportReader(body io.Reader) (func() (*importRecord, error), error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("body must not be empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	// Spreadsheet programs like to start the file with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	columns := map[string]int{"text": -1, "difficulty": -1, "related_words": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; ok {
+			columns[name] = i
+		}
+	}
+
+	if columns["text"] < 0 || columns["difficulty"] < 0 {
+		return nil, errors.New("CSV header must name the text and difficulty columns")
+	}
+
+	field := func(record []string, name string) string {
+		i := columns[name]
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	return func() (*importRecord, error) {
+		record, err := reader.Read()
+		if err != nil {
+			var parseError *csv.ParseError
+			if errors.As(err, &parseError) {
+				return &importRecord{line: parseError.StartLine, err: parseError.Err}, nil
+			}
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		word := &importRecord{
+			Text:         field(record, "text"),
+			Difficulty:   field(record, "difficulty"),
+			RelatedWords: []string{},
+			line:         line,
+		}
+
+		for _, related := range strings.Split(field(record, "related_words"), ";") {
+			if related = strings.TrimSpace(related); related != "" {
+				word.RelatedWords = append(word.RelatedWords, related)
+			}
+		}
+
+		return word, nil
+	}, nil
+}
+
+// ndjsonImportReader returns a function that reads one word per line of JSON.
+// Blank lines are ignored, but still counted in the line numbers.
+func ndjsonImportReader(body io.Reader) func() (*importRecord, error) {
+	reader := bufio.NewReader(body)
+	lineNumber := 0
+
+	return func() (*importRecord, error) {
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				lineNumber++
+			}
+
+			if lineNumber == 1 {
+				line = bytes.TrimPrefix(line, []byte("\ufeff"))
+			}
+
+			if len(bytes.TrimSpace(line)) == 0 {
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+
+			var word importRecord
+
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.DisallowUnknownFields()
+
+			if decodeErr := dec.Decode(&word); decodeErr != nil {
+				return &importRecord{line: lineNumber, err: fmt.Errorf("invalid JSON: %w", decodeErr)}, nil
+			}
+
+			word.line = lineNumber
+			return &word, nil
+		}
+	}
+}
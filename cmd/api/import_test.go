@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// wantRecord is what a test expects an import reader to return for one record
+type wantRecord struct {
+	line       int
+	text       string
+	difficulty string
+	related    []string
+	failed     bool
+}
+
+// checkImportRecords reads next until io.EOF and compares the records with want
+func checkImportRecords(t *testing.T, next func() (*importRecord, error), want []wantRecord) {
+	t.Helper()
+
+	for i := 0; ; i++ {
+		record, err := next()
+		if errors.Is(err, io.EOF) {
+			if i != len(want) {
+				t.Errorf("read %d records, want %d", i, len(want))
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("record %d: unexpected error %v", i, err)
+		}
+		if i >= len(want) {
+			t.Fatalf("record %d: unexpected record %+v", i, record)
+		}
+
+		w := want[i]
+
+		if record.line != w.line {
+			t.Errorf("record %d: line = %d, want %d", i, record.line, w.line)
+		}
+
+		if w.failed {
+			if record.err == nil {
+				t.Errorf("record %d: read %+v, want an error", i, record)
+			}
+			continue
+		}
+
+		if record.err != nil {
+			t.Errorf("record %d: unexpected error %v", i, record.err)
+			continue
+		}
+
+		if record.Text != w.text || record.Difficulty != w.difficulty || !slices.Equal(record.RelatedWords, w.related) {
+			t.Errorf("record %d: read %q, %q, %q, want %q, %q, %q", i,
+				record.Text, record.Difficulty, record.RelatedWords, w.text, w.difficulty, w.related)
+		}
+	}
+}
+
+func TestCSVImportReader(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []wantRecord
+	}{
+		{
+			name: "columns in any order",
+			body: "difficulty,related_words,text\neasy,wind; sky;,kite\n",
+			want: []wantRecord{{line: 2, text: "kite", difficulty: "easy", related: []string{"wind", "sky"}}},
+		},
+		{
+			name: "no related words column",
+			body: "text,difficulty\nkite,easy\n",
+			want: []wantRecord{{line: 2, text: "kite", difficulty: "easy", related: []string{}}},
+		},
+		{
+			name: "byte order mark before the header",
+			body: "\ufefftext,difficulty\nkite,easy\n",
+			want: []wantRecord{{line: 2, text: "kite", difficulty: "easy", related: []string{}}},
+		},
+		{
+			name: "physical line numbers",
+			body: "text,difficulty\r\n" +
+				"kite,easy\r\n" +
+				"\r\n" +
+				"\"two\nlines\",hard\r\n" +
+				"bare\"quote,easy\r\n" +
+				"last,medium",
+			want: []wantRecord{
+				{line: 2, text: "kite", difficulty: "easy", related: []string{}},
+				{line: 4, text: "two\nlines", difficulty: "hard", related: []string{}},
+				{line: 6, failed: true},
+				{line: 7, text: "last", difficulty: "medium", related: []string{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := csvImportReader(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("csvImportReader() error = %v", err)
+			}
+
+			checkImportRecords(t, next, tt.want)
+		})
+	}
+}
+
+func TestCSVImportReaderBadHeader(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty body", ""},
+		{"no text column", "word,difficulty\nkite,easy\n"},
+		{"no difficulty column", "text,related_words\nkite,sky\n"},
+		{"byte order mark not at the start", "difficulty,\ufefftext\neasy,kite\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := csvImportReader(strings.NewReader(tt.body)); err == nil {
+				t.Error("csvImportReader() accepted the header")
+			}
+		})
+	}
+}
+
+func TestNDJSONImportReader(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []wantRecord
+	}{
+		{
+			name: "one word per line",
+			body: "{\"text\":\"kite\",\"difficulty\":\"easy\",\"related_words\":[\"wind\"]}\n" +
+				"{\"text\":\"sky\",\"difficulty\":\"hard\"}\n",
+			want: []wantRecord{
+				{line: 1, text: "kite", difficulty: "easy", related: []string{"wind"}},
+				{line: 2, text: "sky", difficulty: "hard"},
+			},
+		},
+		{
+			name: "byte order mark before the first line",
+			body: "\ufeff{\"text\":\"kite\",\"difficulty\":\"easy\"}\n",
+			want: []wantRecord{{line: 1, text: "kite", difficulty: "easy"}},
+		},
+		{
+			name: "blank lines counted and last line without a newline",
+			body: "\n" +
+				"{\"text\":\"kite\",\"difficulty\":\"easy\"}\r\n" +
+				"   \n" +
+				"\n" +
+				"{\"text\":\"last\",\"difficulty\":\"hard\"}",
+			want: []wantRecord{
+				{line: 2, text: "kite", difficulty: "easy"},
+				{line: 5, text: "last", difficulty: "hard"},
+			},
+		},
+		{
+			name: "invalid lines",
+			body: "{\"text\":\"kite\",\"difficulty\":\"easy\"}\n" +
+				"{not json\n" +
+				"{\"text\":\"sky\",\"difficulty\":\"easy\",\"colour\":\"blue\"}\n" +
+				"{\"text\":\"last\",\"difficulty\":\"hard\"}\n",
+			want: []wantRecord{
+				{line: 1, text: "kite", difficulty: "easy"},
+				{line: 2, failed: true},
+				{line: 3, failed: true},
+				{line: 4, text: "last", difficulty: "hard"},
+			},
+		},
+		{
+			name: "empty body",
+			body: "",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkImportRecords(t, ndjsonImportReader(strings.NewReader(tt.body)), tt.want)
+		})
+	}
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
 	"fmt"
 	"kite-api/internal/data"
+	"kite-api/internal/jwt"
 	"kite-api/internal/mailer"
 	"kite-api/internal/vcs"
 	"log/slog"
@@ -47,14 +49,34 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	permissions struct {
+		cacheTTL time.Duration
+	}
+	words struct {
+		trashRetention time.Duration
+		indexRefresh   time.Duration
+	}
+	auth struct {
+		mode       string
+		accessTTL  time.Duration
+		refreshTTL time.Duration
+		jwt        struct {
+			algorithm    string
+			keys         map[string]string
+			signingKeyID string
+			issuer       string
+		}
+	}
 }
 
 type application struct {
-	config config
-	logger *slog.Logger
-	models data.Models // contains all the models in the app
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config  config
+	logger  *slog.Logger
+	models  data.Models // contains all the models in the app
+	mailer  mailer.Mailer
+	jwt     *jwt.Keyset   // nil unless the API runs in JWT authentication mode
+	revoked *jwt.Denylist // JWT sessions signed out before their tokens expire, nil in token mode
+	wg      sync.WaitGroup
 }
 
 func main() {
@@ -85,6 +107,33 @@ func main() {
 		return nil
 	})
 
+	flag.DurationVar(&cfg.words.trashRetention, "words-trash-retention", 30*24*time.Hour, "How long deleted words stay restorable before they are purged (0 keeps them forever)")
+	flag.DurationVar(&cfg.words.indexRefresh, "words-index-refresh", 10*time.Minute, "How often the word suggestions index is reloaded from the database (0 loads it once)")
+	flag.DurationVar(&cfg.permissions.cacheTTL, "permissions-cache-ttl", time.Minute, "How long users' permissions are cached (0 disables the cache)")
+
+	// Access tokens are short-lived, clients trade a single-use refresh token for a new pair when they expire.
+	flag.DurationVar(&cfg.auth.accessTTL, "auth-access-ttl", 15*time.Minute, "Authentication (access) token lifetime")
+	flag.DurationVar(&cfg.auth.refreshTTL, "auth-refresh-ttl", 30*24*time.Hour, "Refresh token lifetime")
+
+	// In jwt mode authentication tokens are signed JWTs that are verified from their signature and claims,
+	// without a database lookup. Revoked ones are kept on an in-memory denylist until they expire,
+	// so keep auth-access-ttl short: it bounds how long a token survives a restart or another instance.
+	flag.StringVar(&cfg.auth.mode, "auth-mode", "token", "Authentication token type (token|jwt)")
+	flag.StringVar(&cfg.auth.jwt.algorithm, "jwt-alg", jwt.AlgHS256, "JWT signing algorithm (HS256|EdDSA)")
+	flag.Func("jwt-keys", "JWT keys as kid=path pairs (space separated)", func(val string) error {
+		cfg.auth.jwt.keys = make(map[string]string)
+		for _, pair := range strings.Fields(val) {
+			kid, path, found := strings.Cut(pair, "=")
+			if !found || kid == "" || path == "" {
+				return fmt.Errorf("invalid JWT key %q, expected kid=path", pair)
+			}
+			cfg.auth.jwt.keys[kid] = path
+		}
+		return nil
+	})
+	flag.StringVar(&cfg.auth.jwt.signingKeyID, "jwt-kid", "", "ID of the JWT key that signs new tokens")
+	flag.StringVar(&cfg.auth.jwt.issuer, "jwt-issuer", "kite-api", "JWT issuer")
+
 	displayVersion := flag.Bool("version", false, "Display version and exit")
 
 	flag.Parse()
@@ -105,6 +154,21 @@ func main() {
 
 	defer db.Close()
 
+	var keyset *jwt.Keyset
+
+	switch cfg.auth.mode {
+	case "token":
+	case "jwt":
+		keyset, err = openJWTKeyset(cfg)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+	default:
+		logger.Error("invalid auth mode", "mode", cfg.auth.mode)
+		os.Exit(1)
+	}
+
 	logger.Info("Connected to database", "dsn", cfg.db.dsn)
 
 	expvar.NewString("version").Set(version)
@@ -120,11 +184,22 @@ func main() {
 		return time.Now().Unix()
 	}))
 
+	models := data.NewModels(db, cfg.permissions.cacheTTL)
+
+	expvar.Publish("permissions_cache", expvar.Func(func() any {
+		return models.Permissions.Cache.Stats()
+	}))
+
 	app := application{
 		config: cfg,
 		logger: logger,
-		models: data.NewModels(db),
+		models: models,
 		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		jwt:    keyset,
+	}
+
+	if keyset != nil {
+		app.revoked = jwt.NewDenylist(cfg.auth.accessTTL)
 	}
 
 	err = app.serve()
@@ -161,5 +236,25 @@ func openDB(cfg config) (*sql.DB, error) {
 	return db, nil
 }
 
+// openJWTKeyset loads every configured JWT key. Keys other than the signing key
+// are only used to verify tokens issued before the last key rotation.
+func openJWTKeyset(cfg config) (*jwt.Keyset, error) {
+	if len(cfg.auth.jwt.keys) == 0 {
+		return nil, errors.New("jwt auth mode needs at least one key in -jwt-keys")
+	}
+
+	keys := make([]*jwt.Key, 0, len(cfg.auth.jwt.keys))
+
+	for kid, path := range cfg.auth.jwt.keys {
+		key, err := jwt.LoadKey(kid, cfg.auth.jwt.algorithm, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return jwt.NewKeyset(cfg.auth.jwt.issuer, cfg.auth.jwt.signingKeyID, keys...)
+}
+
 //TIP See GoLand help at <a href="https://www.jetbrains.com/help/go/">jetbrains.com/help/go/</a>.
 // Also, you can try interactive lessons for GoLand by selecting 'Help | Learn IDE Features' from the main menu.
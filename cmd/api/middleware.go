@@ -7,10 +7,11 @@ import (
 	"github.com/tomasen/realip"
 	"golang.org/x/time/rate"
 	"kite-api/internal/data"
+	"kite-api/internal/jwt"
 	"kite-api/internal/validator"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -19,6 +20,12 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// A handler aborting a response it has already started wants the
+				// connection cut, which net/http does when it sees the panic
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				w.Header().Set("Connection", "close")
 				app.serverErrorResponse(w, r, fmt.Errorf("%v", err))
 			}
@@ -97,13 +104,42 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		headerParts := strings.SplitN(authorisationHeader, " ", 2)
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		token, ok := app.bearerToken(r)
+		if !ok {
 			app.invalidCredentialsResponse(w, r)
 			return
 		}
 
-		token := headerParts[1]
+		// In JWT mode the token is trusted on its signature and claims alone, so requests don't
+		// hit the database to authenticate. Signing out, deactivation and password changes put the
+		// token on the denylist, and the short access token lifetime covers what it can't see:
+		// refreshing checks the account again.
+		// Opaque database tokens are still accepted, e.g. the ones issued before switching modes.
+		if app.jwt != nil && jwt.LooksLikeJWT(token) {
+			claims, err := app.jwt.Verify(token)
+			if err != nil || app.revoked.Revoked(claims) {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			id, err := strconv.ParseInt(claims.Subject, 10, 64)
+			if err != nil || id < 1 {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+
+			user := &data.User{
+				ID:        id,
+				Name:      claims.Name,
+				Email:     claims.Email,
+				Activated: claims.Activated,
+			}
+
+			r = app.contextSetUser(r, user)
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		v := validator.New()
 
 		if data.ValidateTokenPlainText(v, token); !v.Valid() {
@@ -122,6 +158,11 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		if user.IsDisabled() {
+			app.disabledAccountResponse(w, r)
+			return
+		}
+
 		r = app.contextSetUser(r, user)
 		next.ServeHTTP(w, r)
 	})
@@ -156,6 +197,38 @@ func (app *application) requireActivatedUser(next http.HandlerFunc) http.Handler
 }
 
 func (app *application) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
+	return app.requireAnyPermission([]string{code}, next)
+}
+
+// requireAnyPermission lets the request through when the user holds at least one of the codes,
+// which is how a route is opened up to several roles at once
+func (app *application) requireAnyPermission(codes []string, next http.HandlerFunc) http.HandlerFunc {
+	checkPermissionCodes(codes)
+
+	return app.requirePermissions(func(permissions data.Permissions) bool {
+		return permissions.IncludeAny(codes...)
+	}, next)
+}
+
+// requireAllPermissions lets the request through only when the user holds every one of the codes
+func (app *application) requireAllPermissions(codes []string, next http.HandlerFunc) http.HandlerFunc {
+	checkPermissionCodes(codes)
+
+	return app.requirePermissions(func(permissions data.Permissions) bool {
+		return permissions.IncludeAll(codes...)
+	}, next)
+}
+
+// checkPermissionCodes panics on a route guarded by no permission code or an empty one.
+// Routes are set up at start-up, so the mistake stops the server instead of leaving
+// the route open to everybody or closed to everybody.
+func checkPermissionCodes(codes []string) {
+	if len(codes) == 0 || slices.Contains(codes, "") {
+		panic(fmt.Sprintf("invalid permission codes %q, a route needs at least one and none can be empty", codes))
+	}
+}
+
+func (app *application) requirePermissions(allowed func(data.Permissions) bool, next http.HandlerFunc) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		user := app.contextGetUser(r)
 
@@ -166,7 +239,7 @@ func (app *application) requirePermission(code string, next http.HandlerFunc) ht
 		}
 
 		// return a 403 Forbidden response
-		if !permissions.Include(code) {
+		if !allowed(permissions) {
 			app.notPermittedResponse(w, r)
 			return
 		}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"kite-api/internal/data"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestApplication() *application {
+	return &application{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestRequirePermissionsRejectsEmptyCodes(t *testing.T) {
+	app := newTestApplication()
+	next := func(w http.ResponseWriter, r *http.Request) {}
+
+	guards := map[string]func([]string, http.HandlerFunc) http.HandlerFunc{
+		"any": app.requireAnyPermission,
+		"all": app.requireAllPermissions,
+	}
+
+	tests := []struct {
+		name  string
+		codes []string
+	}{
+		{"nil", nil},
+		{"no codes", []string{}},
+		{"empty code", []string{""}},
+		{"empty code among others", []string{"words:read", ""}},
+	}
+
+	for guard, require := range guards {
+		for _, tt := range tests {
+			t.Run(guard+"/"+tt.name, func(t *testing.T) {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("require %s permission of %q did not panic", guard, tt.codes)
+					}
+				}()
+
+				require(tt.codes, next)
+			})
+		}
+	}
+}
+
+func TestRequirePermissionsGuardsBeforeLookup(t *testing.T) {
+	app := newTestApplication()
+
+	guards := map[string]http.HandlerFunc{
+		"permission": app.requirePermission("words:read", nil),
+		"any":        app.requireAnyPermission([]string{"words:read", "words:write"}, nil),
+		"all":        app.requireAllPermissions([]string{"words:read", "words:write"}, nil),
+	}
+
+	tests := []struct {
+		name string
+		user *data.User
+		want int
+	}{
+		{"anonymous", data.AnonymousUser, http.StatusUnauthorized},
+		{"not activated", &data.User{ID: 1, Activated: false}, http.StatusForbidden},
+	}
+
+	for guard, handler := range guards {
+		for _, tt := range tests {
+			t.Run(guard+"/"+tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				r := app.contextSetUser(httptest.NewRequest(http.MethodGet, "/", nil), tt.user)
+
+				// next is nil, so getting past the guard panics
+				handler(rr, r)
+
+				if rr.Code != tt.want {
+					t.Errorf("status = %d, want %d", rr.Code, tt.want)
+				}
+			})
+		}
+	}
+}
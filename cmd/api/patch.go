@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kite-api/internal/data"
+	"kite-api/internal/jsonpatch"
+	"mime"
+	"net/http"
+)
+
+// The patch formats updateWordHandler understands. Plain application/json is
+// treated as a merge patch, which is what clients have always been sending.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// nullableString is a string member of a merge patch. Set tells a member that was
+// left out apart from one that was sent; Value is nil when it was sent as null.
+type nullableString struct {
+	Set   bool
+	Value *string
+}
+
+func (n *nullableString) UnmarshalJSON(b []byte) error {
+	n.Set = true
+
+	if string(b) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	return json.Unmarshal(b, &n.Value)
+}
+
+// nullableStrings is a string array member of a merge patch. Set tells a member
+// that was left out apart from one that was sent; Values is nil when it was sent as null.
+type nullableStrings struct {
+	Set    bool
+	Values []string
+}
+
+func (n *nullableStrings) UnmarshalJSON(b []byte) error {
+	n.Set = true
+
+	if string(b) == "null" {
+		n.Values = nil
+		return nil
+	}
+
+	return json.Unmarshal(b, &n.Values)
+}
+
+// wordPatch holds the changes a client asked for. A member that wasn't sent leaves the field as it is.
+type wordPatch struct {
+	Text         nullableString  `json:"text"`
+	Difficulty   nullableString  `json:"difficulty"`
+	RelatedWords nullableStrings `json:"related_words"`
+	Reason       string          `json:"reason"`
+}
+
+// applyTo copies the requested changes onto the word. A null related_words clears the list,
+// as RFC 7396 says a null member removes the target. The text and difficulty can't be removed,
+// so a null one blanks the field and fails validation.
+func (p *wordPatch) applyTo(word *data.Word) {
+	if p.Text.Set {
+		word.TextValue = ""
+		if p.Text.Value != nil {
+			word.TextValue = *p.Text.Value
+		}
+	}
+
+	if p.Difficulty.Set {
+		word.Difficulty = ""
+		if p.Difficulty.Value != nil {
+			word.Difficulty = *p.Difficulty.Value
+		}
+	}
+
+	if p.RelatedWords.Set {
+		word.RelatedWords = p.RelatedWords.Values
+		if word.RelatedWords == nil {
+			word.RelatedWords = []string{}
+		}
+	}
+}
+
+// readWordPatch reads the changes to the word from the request body, either as a
+// JSON Merge Patch (RFC 7396) or as a JSON Patch (RFC 6902) depending on the Content-Type.
+// It sends the error response itself and returns false when the body can't be used.
+func (app *application) readWordPatch(w http.ResponseWriter, r *http.Request, word *data.Word) (*wordPatch, bool) {
+	var patch wordPatch
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		var err error
+
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			app.badRequestResponse(w, r, "malformed Content-Type header")
+			return nil, false
+		}
+	}
+
+	switch mediaType {
+	case "application/json", mergePatchType:
+		err := app.readJSON(w, r, &patch)
+		if err != nil {
+			app.badRequestResponse(w, r, err.Error())
+			return nil, false
+		}
+	case jsonPatchType:
+		var operations []jsonpatch.Operation
+
+		err := app.readJSON(w, r, &operations)
+		if err != nil {
+			app.badRequestResponse(w, r, err.Error())
+			return nil, false
+		}
+
+		// The operations work on the editable fields of the word,
+		// e.g. {"op": "add", "path": "/related_words/-", "value": "kite"}
+		document, err := json.Marshal(map[string]any{
+			"text":          word.TextValue,
+			"difficulty":    word.Difficulty,
+			"related_words": word.RelatedWords,
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return nil, false
+		}
+
+		patched, err := jsonpatch.Apply(document, operations)
+		if err != nil {
+			switch {
+			case errors.Is(err, jsonpatch.ErrTestFailed):
+				app.errorResponse(w, r, http.StatusConflict, err.Error())
+			default:
+				app.errorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+			}
+			return nil, false
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(patched))
+		dec.DisallowUnknownFields()
+
+		err = dec.Decode(&patch)
+		if err != nil {
+			app.errorResponse(w, r, http.StatusUnprocessableEntity, fmt.Sprintf("patched word is invalid: %s", err))
+			return nil, false
+		}
+
+		// Every field was in the document, so one missing from the result was removed
+		patch.Text.Set = true
+		patch.Difficulty.Set = true
+		patch.RelatedWords.Set = true
+
+		// There is nowhere in the word document for the reason, so it comes in the query string
+		patch.Reason = app.readString(r.URL.Query(), "reason", "")
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		app.errorResponse(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("unsupported Content-Type %q", mediaType))
+		return nil, false
+	}
+
+	return &patch, true
+}
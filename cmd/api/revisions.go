@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"kite-api/internal/data"
+	"kite-api/internal/validator"
+	"math"
+	"net/http"
+	"slices"
+)
+
+// listWordRevisionsHandler shows the history of a word, newest version first,
+// with who made each change and why.
+func (app *application) listWordRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	statuses, err := app.revisionStatuses(r, word)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	revisions, err := app.models.Revisions.GetAllForWord(word.ID, statuses)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showWordRevisionHandler returns one version of a word together with
+// the fields that changed since the version before it.
+func (app *application) showWordRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	revision, ok := app.readRevision(w, r, word)
+	if !ok {
+		return
+	}
+
+	statuses, err := app.revisionStatuses(r, word)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(statuses) > 0 && !slices.Contains(statuses, revision.Status) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// The changes are worked out against a version the user may see as well
+	previous, err := app.models.Revisions.GetPrevious(word.ID, revision.Version, statuses)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"revision": revision, "changes": revision.Diff(previous)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// revertWordHandler copies the content of an earlier version back onto the word.
+// The revert is a new version in its own right and, like any edit, has to be reviewed again.
+func (app *application) revertWordHandler(w http.ResponseWriter, r *http.Request) {
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canModifyWord(w, r, word) {
+		return
+	}
+
+	if !app.checkIfMatch(w, r, int64(word.Version)) {
+		return
+	}
+
+	revision, ok := app.readRevision(w, r, word)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	// The body is optional
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err.Error())
+			return
+		}
+	}
+
+	if input.Reason == "" {
+		input.Reason = fmt.Sprintf("reverted to version %d", revision.Version)
+	}
+
+	v := validator.New()
+
+	if v.Check(len(input.Reason) <= 500, "reason", "must be 500 or less characters"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	word.TextValue = revision.TextValue
+	word.Difficulty = revision.Difficulty
+	word.RelatedWords = revision.RelatedWords
+	word.Status = data.StatusDraft
+
+	err := app.models.Words.Update(word, app.contextGetUser(r).ID, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateWord):
+			v.AddError("text", "another word already has the text of this version")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(int64(word.Version)))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"word": word}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readWord looks up the word addressed by the :id path parameter and checks the user may see it.
+// It sends the error response itself and returns false when there is no such word.
+func (app *application) readWord(w http.ResponseWriter, r *http.Request) (*data.Word, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	word, err := app.models.Words.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	canRead, err := app.canReadWord(r, word)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, false
+	}
+
+	if !canRead {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	return word, true
+}
+
+// revisionStatuses works out which review states of a word's history the user may see.
+// The owner of the word and reviewers holding approvals:read see every version,
+// anybody else only the approved ones.
+func (app *application) revisionStatuses(r *http.Request, word *data.Word) ([]string, error) {
+	if word.UserId == app.contextGetUser(r).ID {
+		return nil, nil
+	}
+
+	canRead, err := app.canReadUnapproved(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !canRead {
+		return []string{data.StatusApproved}, nil
+	}
+
+	return nil, nil
+}
+
+// readRevision looks up the version of the word addressed by the :version path parameter.
+// It sends the error response itself and returns false when there is no such version.
+func (app *application) readRevision(w http.ResponseWriter, r *http.Request, word *data.Word) (*data.WordRevision, bool) {
+	version, err := app.readNamedIDParam(r, "version")
+	if err != nil || version > math.MaxInt32 {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	revision, err := app.models.Revisions.Get(word.ID, int32(version))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return revision, true
+}
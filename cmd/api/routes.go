@@ -18,16 +18,97 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodGet, "/api/v1/words", app.requirePermission("words:read", app.listWordsHandler))
 	router.HandlerFunc(http.MethodPost, "/api/v1/words", app.requirePermission("words:write", app.createWordHandler))
-	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id", app.requirePermission("words:read", app.getWordHandler))
+	// httprouter won't register a static segment next to the :id wildcard,
+	// so fixed paths such as /words/trash or /words/import are dispatched on the value of :id.
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id", app.staticSegments("id", map[string]http.HandlerFunc{
+		"trash":   app.requirePermission("words:moderate", app.listDeletedWordsHandler),
+		"export":  app.requirePermission("words:read", app.exportWordsHandler),
+		"suggest": app.requirePermission("words:read", app.suggestWordsHandler),
+	}, app.requirePermission("words:read", app.getWordHandler)))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id", app.staticSegments("id", map[string]http.HandlerFunc{
+		"import": app.requirePermission("words:write", app.importWordsHandler),
+	}, app.methodNotAllowedResponse))
 	router.HandlerFunc(http.MethodPatch, "/api/v1/words/:id", app.requirePermission("words:write", app.updateWordHandler))
 	router.HandlerFunc(http.MethodDelete, "/api/v1/words/:id", app.requirePermission("words:write", app.deleteWordHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/restore", app.requirePermission("words:write", app.restoreWordHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/revisions", app.requirePermission("words:read", app.listWordRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/revisions/:version", app.requirePermission("words:read", app.showWordRevisionHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/revert/:version", app.requirePermission("words:write", app.revertWordHandler))
+
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/submit", app.requirePermission("words:write", app.submitWordHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/review", app.requirePermission("approvals:write", app.reviewWordHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/approvals", app.requirePermission("approvals:read", app.listWordApprovalsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/translations", app.requirePermission("translations:read", app.listTranslationsHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/translations", app.requirePermission("translations:write", app.createTranslationHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/translations/:translation_id", app.requirePermission("translations:read", app.getTranslationHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/words/:id/translations/:translation_id", app.requirePermission("translations:write", app.updateTranslationHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/words/:id/translations/:translation_id", app.requirePermission("translations:write", app.deleteTranslationHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/translations/:translation_id/submit", app.requirePermission("translations:write", app.submitTranslationHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/translations/:translation_id/review", app.requirePermission("approvals:write", app.reviewTranslationHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/translations/:translation_id/approvals", app.requirePermission("approvals:read", app.listTranslationApprovalsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/words/:id/feedback", app.requirePermission("feedbacks:read", app.listWordFeedbackHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/words/:id/feedback", app.requirePermission("feedbacks:write", app.createFeedbackHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/feedback", app.requirePermission("feedbacks:read", app.listFeedbackHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/feedback/:id", app.requirePermission("feedbacks:read", app.getFeedbackHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/feedback/:id", app.requirePermission("feedbacks:moderate", app.updateFeedbackHandler))
 
 	router.HandlerFunc(http.MethodPost, "/api/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/api/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodPut, "/api/v1/users/email", app.confirmEmailHandler)
+	router.HandlerFunc(http.MethodGet, "/api/v1/users/me", app.requireAuthenticatedUser(app.showCurrentUserHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/users/me", app.requireActivatedUser(app.updateCurrentUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/users/me", app.requireAuthenticatedUser(app.deleteCurrentUserHandler))
+	router.HandlerFunc(http.MethodPut, "/api/v1/users/me/password", app.requireActivatedUser(app.updateCurrentUserPasswordHandler))
+	router.HandlerFunc(http.MethodPut, "/api/v1/users/password", app.resetUserPasswordHandler)
 	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodGet, "/api/v1/tokens/authentication", app.requireAuthenticatedUser(app.listAuthenticationTokensHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/tokens/authentication", app.requireAuthenticatedUser(app.deleteAuthenticationTokenHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/tokens/authentication/:id", app.requireAuthenticatedUser(app.staticSegments("id", map[string]http.HandlerFunc{
+		"all": app.deleteAllAuthenticationTokensHandler,
+	}, app.deleteSessionHandler)))
+	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/refresh", app.refreshAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/activation", app.createActivationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/password-reset", app.createPasswordResetTokenHandler)
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/permissions", app.requirePermission("users:admin", app.listPermissionsHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/users", app.requirePermission("users:admin", app.listUsersHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/users/:id", app.requirePermission("users:admin", app.showUserHandler))
+	router.HandlerFunc(http.MethodPut, "/api/v1/admin/users/:id/deactivated", app.requirePermission("users:admin", app.deactivateUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/admin/users/:id/deactivated", app.requirePermission("users:admin", app.reactivateUserHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/users/:id/permissions", app.requirePermission("users:admin", app.listUserPermissionsHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/admin/users/:id/permissions", app.requirePermission("users:admin", app.grantUserPermissionsHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/admin/users/:id/permissions/:code", app.requirePermission("users:admin", app.revokeUserPermissionHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/users/:id/roles", app.requirePermission("users:admin", app.listUserRolesHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/admin/users/:id/roles", app.requirePermission("users:admin", app.assignUserRolesHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/admin/users/:id/roles/:code", app.requirePermission("users:admin", app.removeUserRoleHandler))
+
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/roles", app.requirePermission("users:admin", app.listRolesHandler))
+	router.HandlerFunc(http.MethodPost, "/api/v1/admin/roles", app.requirePermission("users:admin", app.createRoleHandler))
+	router.HandlerFunc(http.MethodGet, "/api/v1/admin/roles/:id", app.requirePermission("users:admin", app.showRoleHandler))
+	router.HandlerFunc(http.MethodPatch, "/api/v1/admin/roles/:id", app.requirePermission("users:admin", app.updateRoleHandler))
+	router.HandlerFunc(http.MethodDelete, "/api/v1/admin/roles/:id", app.requirePermission("users:admin", app.deleteRoleHandler))
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 	// this whole api now has CORS enabled.
 	//return app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router))))
 	return app.metrics(app.recoverPanic(app.rateLimit(app.authenticate(router))))
 }
+
+// staticSegments routes a request to the handler registered for the value of the named
+// path parameter, falling back to next for any other value.
+func (app *application) staticSegments(param string, handlers map[string]http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value := httprouter.ParamsFromContext(r.Context()).ByName(param)
+
+		if handler, ok := handlers[value]; ok {
+			handler(w, r)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -22,6 +22,20 @@ func (app *application) serve() error {
 		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
 	}
 
+	// Background jobs run until the server starts shutting down
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	if app.config.words.trashRetention > 0 {
+		app.background(func() {
+			app.purgeDeletedWords(jobs)
+		})
+	}
+
+	app.background(func() {
+		app.refreshWordIndex(jobs)
+	})
+
 	shutdownError := make(chan error)
 	go func() {
 		// Create a quit channel which carries os.Signal values
@@ -45,6 +59,8 @@ func (app *application) serve() error {
 
 		app.logger.Info("completing background tasks", "addr", srv.Addr)
 
+		stopJobs()
+
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
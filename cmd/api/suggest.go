@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"kite-api/internal/validator"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// suggestWordsHandler returns the approved words starting with ?q=, for typeahead.
+// It answers from the in-memory word index, so it is cheap enough to call on every keystroke.
+func (app *application) suggestWordsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	qs := r.URL.Query()
+
+	prefix := app.readString(qs, "q", "")
+	limit := app.readInt(qs, "limit", 10, v)
+
+	v.Check(prefix != "", "q", "must be provided")
+	v.Check(utf8.RuneCountInString(prefix) <= 100, "q", "must not be more than 100 characters long")
+	v.Check(limit > 0, "limit", "must be greater than zero")
+	v.Check(limit <= 50, "limit", "must be no greater than 50")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.models.Words.Suggest(prefix, limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// refreshWordIndex loads the word suggestions index, then reloads it at the configured
+// interval to pick up changes made by other instances, until ctx is cancelled.
+// Until the first load completes suggestions are served from the database.
+func (app *application) refreshWordIndex(ctx context.Context) {
+	for {
+		start := time.Now()
+
+		err := app.models.Words.Index.Load(app.models.Words.DB)
+		if err != nil {
+			app.logger.Error("loading word index", "error", err.Error())
+		} else {
+			app.logger.Debug("loaded word index", "duration", time.Since(start).String())
+		}
+
+		if app.config.words.indexRefresh <= 0 && err == nil {
+			return
+		}
+
+		// Retry a failed first load after a minute rather than waiting for the next refresh
+		wait := app.config.words.indexRefresh
+		if wait <= 0 || (err != nil && !app.models.Words.Index.Ready()) {
+			wait = time.Minute
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
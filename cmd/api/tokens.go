@@ -0,0 +1,430 @@
+package main
+
+import (
+	"errors"
+	"github.com/julienschmidt/httprouter"
+	"github.com/tomasen/realip"
+	"kite-api/internal/data"
+	"kite-api/internal/jwt"
+	"kite-api/internal/validator"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (app *application) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// The response is the same whether or not the email belongs to an account,
+	// so this endpoint cannot be used to find out who has signed up.
+	message := "if an activated account exists for this email address, you will receive an email with password reset instructions"
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.writeAcceptedMessage(w, r, message)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !user.Activated || user.IsDisabled() {
+		app.writeAcceptedMessage(w, r, message)
+		return
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 45*time.Minute, data.ScopePasswordReset)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.background(func() {
+		tokenData := map[string]any{
+			"passwordResetToken": token.PlainText,
+		}
+
+		err := app.mailer.Send(user.Email, "token_password_reset.tmpl", tokenData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	app.writeAcceptedMessage(w, r, message)
+}
+
+// writeAcceptedMessage sends a 202 Accepted with a message, for requests whose real work happens in the background
+func (app *application) writeAcceptedMessage(w http.ResponseWriter, r *http.Request, message string) {
+	err := app.writeJSON(w, http.StatusAccepted, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// createActivationTokenHandler sends a fresh activation token to a user whose welcome email was lost or expired
+func (app *application) createActivationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Unknown and already activated accounts get the same answer as everybody else,
+	// so this endpoint cannot be used to find out who has signed up.
+	message := "if an account awaiting activation exists for this email address, you will receive an email with activation instructions"
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.writeAcceptedMessage(w, r, message)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.Activated || user.IsDisabled() {
+		app.writeAcceptedMessage(w, r, message)
+		return
+	}
+
+	err = app.sendActivationToken(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeAcceptedMessage(w, r, message)
+}
+
+// sendActivationToken replaces the user's activation tokens with a new one and emails it to them
+func (app *application) sendActivationToken(user *data.User) error {
+	// Only the newest activation token should be usable
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeActivation, user.ID)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeActivation)
+	if err != nil {
+		return err
+	}
+
+	app.background(func() {
+		tokenData := map[string]any{
+			"activationToken": token.PlainText,
+		}
+
+		err := app.mailer.Send(user.Email, "token_activation.tmpl", tokenData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	return nil
+}
+
+// sendEmailChangeToken replaces the user's email change tokens with a new one and emails
+// it to the address waiting to be confirmed
+func (app *application) sendEmailChangeToken(user *data.User) error {
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeEmailChange, user.ID)
+	if err != nil {
+		return err
+	}
+
+	token, err := app.models.Tokens.New(user.ID, 24*time.Hour, data.ScopeEmailChange)
+	if err != nil {
+		return err
+	}
+
+	email := *user.PendingEmail
+
+	app.background(func() {
+		tokenData := map[string]any{
+			"email":            email,
+			"emailChangeToken": token.PlainText,
+		}
+
+		err := app.mailer.Send(email, "token_email_change.tmpl", tokenData)
+		if err != nil {
+			app.logger.Error(err.Error())
+		}
+	})
+
+	return nil
+}
+
+// currentSession identifies the login the request was made with: by its opaque access token,
+// or for a JWT, which isn't stored, by the token family named in its sid claim.
+func (app *application) currentSession(r *http.Request) (string, string) {
+	token, _ := app.bearerToken(r)
+
+	if app.jwt != nil && jwt.LooksLikeJWT(token) {
+		claims, err := app.jwt.Verify(token)
+		if err != nil {
+			return "", ""
+		}
+
+		return "", claims.SessionID
+	}
+
+	return token, ""
+}
+
+// listAuthenticationTokensHandler lists the current user's active sessions, one per login
+func (app *application) listAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	token, family := app.currentSession(r)
+
+	sessions, err := app.models.Tokens.GetSessionsForUser(user.ID, token, family)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"sessions": sessions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAuthenticationTokenHandler revokes the bearer token the request was made with, i.e. logs out
+func (app *application) deleteAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := app.bearerToken(r)
+	if !ok {
+		app.invalidAuthenticationTokenResponse(w, r)
+		return
+	}
+
+	// A JWT is not stored anywhere, but it only works while its session has a refresh token,
+	// so revoking those signs it out.
+	if app.jwt != nil && jwt.LooksLikeJWT(token) {
+		claims, err := app.jwt.Verify(token)
+		if err != nil {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		if claims.SessionID != "" {
+			err = app.models.Tokens.DeleteFamily(claims.SessionID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+
+			app.revoked.RevokeSession(claims.SessionID)
+		}
+
+		err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been signed out"}, nil)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err := app.models.Tokens.Delete(data.ScopeAuthentication, token)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been signed out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteSessionHandler signs out one of the current user's sessions, picked by the id it is listed with.
+// Every token of the session goes, so its refresh token can't bring it back.
+func (app *application) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	family := httprouter.ParamsFromContext(r.Context()).ByName("id")
+
+	// Scoped to the current user, so nobody can sign out somebody else's session
+	err := app.models.Tokens.DeleteFamilyForUser(app.contextGetUser(r).ID, family)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.revoked.RevokeSession(family)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "the session has been signed out"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAllAuthenticationTokensHandler revokes every authentication token of the current user,
+// signing them out on all their devices
+func (app *application) deleteAllAuthenticationTokensHandler(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	err := app.models.Tokens.DeleteAllForUser(data.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.models.Tokens.DeleteAllForUser(data.ScopeRefresh, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.revoked.RevokeUser(user.ID)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "you have been signed out on all devices"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// issueAuthenticationTokens creates a short-lived access token and a single-use refresh token
+// for the user, both belonging to the given token family.
+func (app *application) issueAuthenticationTokens(user *data.User, family string) (envelope, error) {
+	var (
+		accessToken string
+		expiry      = time.Now().Add(app.config.auth.accessTTL)
+	)
+
+	if app.jwt != nil {
+		claims := jwt.Claims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			Name:      user.Name,
+			Email:     user.Email,
+			Activated: user.Activated,
+			SessionID: family,
+		}
+
+		token, err := app.jwt.Sign(claims, app.config.auth.accessTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		accessToken = token
+	} else {
+		token, err := app.models.Tokens.NewInFamily(user.ID, app.config.auth.accessTTL, data.ScopeAuthentication, family)
+		if err != nil {
+			return nil, err
+		}
+
+		accessToken = token.PlainText
+		expiry = token.Expiry
+	}
+
+	refreshToken, err := app.models.Tokens.NewInFamily(user.ID, app.config.auth.refreshTTL, data.ScopeRefresh, family)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := envelope{
+		"auth_token":           accessToken,
+		"auth_token_expiry":    expiry,
+		"refresh_token":        refreshToken.PlainText,
+		"refresh_token_expiry": refreshToken.Expiry,
+	}
+
+	return tokens, nil
+}
+
+// refreshAuthenticationTokenHandler trades a refresh token for a new access token and refresh token.
+// Each refresh token works once; presenting it again revokes every token from that login.
+func (app *application) refreshAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlainText(v, input.RefreshToken); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	token, err := app.models.Tokens.Consume(data.ScopeRefresh, input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrTokenReused):
+			app.revoked.RevokeSession(token.Family)
+			app.logger.Warn("refresh token reused, revoked its token family", "ip", realip.FromRequest(r))
+			app.invalidAuthenticationTokenResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.Get(token.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.IsDisabled() {
+		app.disabledAccountResponse(w, r)
+		return
+	}
+
+	tokens, err := app.issueAuthenticationTokens(user, token.Family)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, tokens, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"kite-api/internal/data"
+	"kite-api/internal/validator"
+	"net/http"
+	"strings"
+)
+
+func (app *application) createTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	// The word must exist, and be visible to the user, before they can translate it
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	wordID := word.ID
+
+	var input struct {
+		Language string `json:"language"`
+		Text     string `json:"text"`
+		Notes    string `json:"notes"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	// The author is always the authenticated user, never something taken from the body
+	translation := &data.Translation{
+		WordID:    wordID,
+		Language:  input.Language,
+		TextValue: input.Text,
+		Notes:     input.Notes,
+		AuthorID:  app.contextGetUser(r).ID,
+		Status:    data.StatusDraft,
+	}
+
+	v := validator.New()
+
+	if data.ValidateTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.Insert(translation)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/api/v1/words/%d/translations/%d", wordID, translation.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"translation": translation}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	translation, ok := app.readTranslation(w, r)
+	if !ok {
+		return
+	}
+
+	// Unapproved translations are only visible to their author and to reviewers
+	if translation.Status != data.StatusApproved && translation.AuthorID != app.contextGetUser(r).ID {
+		canRead, err := app.canReadUnapproved(r)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !canRead {
+			app.notFoundResponse(w, r)
+			return
+		}
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	translation, ok := app.readTranslation(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canModifyTranslation(w, r, translation) {
+		return
+	}
+
+	// Pointers let us tell a field that was left out apart from one that was sent empty
+	var input struct {
+		Language *string `json:"language"`
+		Text     *string `json:"text"`
+		Notes    *string `json:"notes"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err.Error())
+		return
+	}
+
+	if input.Language != nil {
+		translation.Language = *input.Language
+	}
+
+	if input.Text != nil {
+		translation.TextValue = *input.Text
+	}
+
+	if input.Notes != nil {
+		translation.Notes = *input.Notes
+	}
+
+	// Any change to the content has to be reviewed again
+	translation.Status = data.StatusDraft
+
+	v := validator.New()
+
+	if data.ValidateTranslation(v, translation); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Translations.Update(translation)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translation": translation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTranslationHandler(w http.ResponseWriter, r *http.Request) {
+	translation, ok := app.readTranslation(w, r)
+	if !ok {
+		return
+	}
+
+	if !app.canModifyTranslation(w, r, translation) {
+		return
+	}
+
+	err := app.models.Translations.Delete(translation.WordID, translation.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "translation successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// canModifyTranslation reports whether the current user may change, submit or delete the
+// translation: its author can, and so can moderators holding translations:moderate.
+// It sends a 403 Forbidden response itself when the answer is no.
+func (app *application) canModifyTranslation(w http.ResponseWriter, r *http.Request, translation *data.Translation) bool {
+	user := app.contextGetUser(r)
+
+	if translation.AuthorID == user.ID {
+		return true
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if !permissions.Include("translations:moderate") {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+
+	return true
+}
+
+func (app *application) listTranslationsHandler(w http.ResponseWriter, r *http.Request) {
+	// Translations of a word nobody may see yet are hidden along with it
+	word, ok := app.readWord(w, r)
+	if !ok {
+		return
+	}
+
+	wordID := word.ID
+
+	var input struct {
+		Language string
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Language = app.readString(qs, "language", "")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+
+	// text is exposed externally as text, but stored as text_value
+	if strings.TrimPrefix(input.Filters.Sort, "-") == "text" {
+		input.Filters.Sort = input.Filters.Sort + "_value"
+	}
+
+	input.Filters.SortSafeList = []string{"id", "language", "text_value", "created_at", "-id", "-language", "-text_value", "-created_at"}
+
+	statuses, err := app.readStatuses(r, qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	translations, metadata, err := app.models.Translations.GetAllForWord(wordID, input.Language, statuses, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"translations": translations, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
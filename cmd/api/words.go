@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"kite-api/internal/data"
@@ -8,16 +9,15 @@ import (
 	"log/slog"
 	"math/rand"
 	"net/http"
-	"strings"
+	"net/url"
 	"time"
 )
 
 func (app *application) createWordHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Text         string      `json:"text"`
-		Difficulty   string      `json:"difficulty"`
-		RelatedWords []string    `json:"related_words"`
-		UserId       data.UserID `json:"user_id"`
+		Text         string   `json:"text"`
+		Difficulty   string   `json:"difficulty"`
+		RelatedWords []string `json:"related_words"`
 	}
 
 	// alternative way => err := json.NewDecoder(r.Body).Decode(&input)
@@ -33,7 +33,8 @@ func (app *application) createWordHandler(w http.ResponseWriter, r *http.Request
 		TextValue:    input.Text,
 		Difficulty:   input.Difficulty,
 		RelatedWords: input.RelatedWords,
-		UserId:       int64(input.UserId),
+		UserId:       app.contextGetUser(r).ID,
+		Status:       data.StatusDraft,
 		CreatedAt:    time.Now(),
 	}
 
@@ -51,7 +52,13 @@ func (app *application) createWordHandler(w http.ResponseWriter, r *http.Request
 	// Insert into the database
 	err = app.models.Words.Insert(word)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateWord):
+			v.AddError("text", "a word with this text already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -87,6 +94,21 @@ func (app *application) getWordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	canRead, err := app.canReadWord(r, word)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if !canRead {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if app.notModified(w, r, int64(word.Version)) {
+		return
+	}
+
 	if word.RelatedWords == nil {
 		word.RelatedWords = []string{}
 	}
@@ -114,7 +136,7 @@ func (app *application) updateWordHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
-			slog.Info("Error: ", err.Error())
+			slog.Info("word not found", "id", id)
 			app.notFoundResponse(w, r)
 		default:
 			app.serverErrorResponse(w, r, err)
@@ -122,45 +144,44 @@ func (app *application) updateWordHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Then we extract the new data from the request body
-	// and update the word object.
-	var input struct {
-		Text         string      `json:"text"`
-		Difficulty   string      `json:"difficulty"`
-		RelatedWords []string    `json:"related_words"`
-		UserId       data.UserID `json:"user_id"`
+	if !app.canModifyWord(w, r, word) {
+		return
 	}
 
-	err = app.readJSON(w, r, &input)
-
-	if err != nil {
-		app.badRequestResponse(w, r, err.Error())
+	// The client may have edited a copy it read long ago
+	if !app.checkIfMatch(w, r, int64(word.Version)) {
 		return
 	}
 
-	// Partial update
-	// Updating the word object with the new values,
-	// but only when it has a value.
-	if strings.Trim(input.Text, " ") != "" {
-		word.TextValue = input.Text
+	// Then we extract the changes from the request body
+	// and apply them to the word object.
+	patch, ok := app.readWordPatch(w, r, word)
+	if !ok {
+		return
 	}
 
-	if strings.Trim(input.Difficulty, " ") != "" {
-		word.Difficulty = input.Difficulty
-	}
+	patch.applyTo(word)
 
-	if input.RelatedWords != nil {
-		word.RelatedWords = input.RelatedWords
-	}
+	// Any change to the content has to be reviewed again
+	word.Status = data.StatusDraft
+
+	v := validator.New()
+
+	v.Check(len(patch.Reason) <= 500, "reason", "must be 500 or less characters")
 
-	if input.UserId > 0 {
-		word.UserId = int64(input.UserId)
+	// An explicit empty value is a real change now, so it has to be checked
+	if data.ValidateWord(v, word); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
 	}
 
 	// Update the database with the new data
-	err = app.models.Words.Update(word)
+	err = app.models.Words.Update(word, app.contextGetUser(r).ID, patch.Reason)
 	if err != nil {
 		switch {
+		case errors.Is(err, data.ErrDuplicateWord):
+			v.AddError("text", "a word with this text already exists")
+			app.failedValidationResponse(w, r, v.Errors)
 		case errors.Is(err, data.ErrEditConflict):
 			app.editConflictResponse(w, r)
 		default:
@@ -169,8 +190,11 @@ func (app *application) updateWordHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(int64(word.Version)))
+
 	// Notify the client with 200 STATUS OK.
-	err = app.writeJSON(w, http.StatusOK, envelope{"word": word}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"word": word}, headers)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -186,8 +210,27 @@ func (app *application) deleteWordHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	word, err := app.models.Words.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.canModifyWord(w, r, word) {
+		return
+	}
+
+	if !app.checkIfMatch(w, r, int64(word.Version)) {
+		return
+	}
+
 	// Delete the word that matches the id.
-	err = app.models.Words.Delete(id)
+	err = app.models.Words.Delete(word.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -207,40 +250,162 @@ func (app *application) deleteWordHandler(w http.ResponseWriter, r *http.Request
 
 func (app *application) listWordsHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Text         string
-		Difficulty   string
-		RelatedWords []string
 		data.Filters
 	}
 
 	v := validator.New()
 	qs := r.URL.Query()
 
-	input.Text = app.readString(qs, "text", "")
-	input.Difficulty = app.readString(qs, "difficulty", "")
-	input.RelatedWords = app.readCSV(qs, "related_words", []string{})
+	search, err := app.readWordSearch(r, qs, v)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 
-	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.Sort = app.readWordSort(qs)
+	input.Filters.SortSafeList = wordSortSafeList
+
+	// Sending a cursor, even an empty one for the first page, switches to keyset
+	// pagination: each response's next_cursor fetches the page after it
+	input.Filters.UseCursor = qs.Has("cursor")
+	input.Filters.Cursor = qs.Get("cursor")
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	words, metadata, err := app.models.Words.GetAll(search, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"words": words, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+var wordSortSafeList = []string{"id", "text_value", "difficulty", "relevance", "-id", "-text_value", "-difficulty", "-relevance"}
+
+// readWordSearch reads the search terms shared by the word listing and the export
+func (app *application) readWordSearch(r *http.Request, qs url.Values, v *validator.Validator) (data.WordSearch, error) {
+	statuses, err := app.readStatuses(r, qs, v)
+	if err != nil {
+		return data.WordSearch{}, err
+	}
+
+	search := data.WordSearch{
+		Text:         app.readString(qs, "text", ""),
+		Mode:         app.readString(qs, "mode", data.SearchFullText),
+		Difficulty:   app.readString(qs, "difficulty", ""),
+		Statuses:     statuses,
+		RelatedWords: app.readCSV(qs, "related_words", []string{}),
+		Match:        app.readString(qs, "match", data.MatchAll),
+	}
+
+	v.Check(validator.IsPermittedValue(search.Mode, data.SearchModes), "mode", "must be one of: fulltext, prefix, fuzzy")
+	v.Check(validator.IsPermittedValue(search.Match, []string{data.MatchAll, data.MatchAny}), "match", "must be one of: all, any")
+
+	return search, nil
+}
+
+// readWordSort reads the sort query string parameter of a word listing
+func (app *application) readWordSort(qs url.Values) string {
+	sort := app.readString(qs, "sort", "id")
 
 	// since our field text_value is only exposed externally as text
 	// we need to append _value at the end before sending it to the database query
+	if sort == "text" || sort == "-text" {
+		sort = sort + "_value"
+	}
+
+	return sort
+}
+
+// canReadWord reports whether the current user may see the word.
+// Unapproved words are only visible to their owner and to reviewers.
+func (app *application) canReadWord(r *http.Request, word *data.Word) (bool, error) {
+	if word.Status == data.StatusApproved || word.UserId == app.contextGetUser(r).ID {
+		return true, nil
+	}
+
+	return app.canReadUnapproved(r)
+}
+
+// canModifyWord reports whether the current user may change or delete the word:
+// its owner can, and so can moderators holding words:moderate.
+// It sends the response itself when the answer is no: 404 Not Found when the user
+// can't see the word either, the same as reading it would, and 403 Forbidden otherwise.
+func (app *application) canModifyWord(w http.ResponseWriter, r *http.Request, word *data.Word) bool {
+	user := app.contextGetUser(r)
+
+	if word.UserId == user.ID {
+		return true
+	}
+
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	if permissions.Include("words:moderate") {
+		return true
+	}
+
+	// Trashed words can't be read by anybody
+	canRead := false
+	if word.DeletedAt == nil {
+		canRead, err = app.canReadWord(r, word)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return false
+		}
+	}
+
+	if !canRead {
+		app.notFoundResponse(w, r)
+		return false
+	}
+
+	app.notPermittedResponse(w, r)
+	return false
+}
+
+// listDeletedWordsHandler shows moderators the trash, most recently deleted first by default
+func (app *application) listDeletedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+
 	if input.Filters.Sort == "text" || input.Filters.Sort == "-text" {
 		input.Filters.Sort = input.Filters.Sort + "_value"
 	}
 
-	input.Filters.SortSafeList = []string{"id", "text_value", "difficulty", "-id", "-text_value", "-difficulty"}
+	input.Filters.SortSafeList = []string{"id", "text_value", "deleted_at", "-id", "-text_value", "-deleted_at"}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	words, metadata, err := app.models.Words.GetAll(input.Text, input.Difficulty, input.Filters)
+	words, metadata, err := app.models.Words.GetAllDeleted(input.Filters)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"words": words, "metadata": metadata}, nil)
@@ -248,3 +413,82 @@ func (app *application) listWordsHandler(w http.ResponseWriter, r *http.Request)
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// restoreWordHandler takes a word back out of the trash.
+// Like deleting, it is open to the word's owner and to moderators.
+func (app *application) restoreWordHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	word, err := app.models.Words.GetDeleted(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !app.canModifyWord(w, r, word) {
+		return
+	}
+
+	// The word may have been edited after the client last saw it, before it went to the trash
+	if !app.checkIfMatch(w, r, int64(word.Version)) {
+		return
+	}
+
+	err = app.models.Words.Restore(word)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateWord):
+			v := validator.New()
+			v.AddError("text", "a word with this text was added while this one was in the trash")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if word.RelatedWords == nil {
+		word.RelatedWords = []string{}
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", versionETag(int64(word.Version)))
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"word": word}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// purgeDeletedWords permanently removes words that have sat in the trash for longer
+// than the configured retention. It checks once an hour until ctx is cancelled.
+func (app *application) purgeDeletedWords(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		purged, err := app.models.Words.Purge(time.Now().Add(-app.config.words.trashRetention))
+		if err != nil {
+			app.logger.Error("purging deleted words", "error", err.Error())
+		} else if purged > 0 {
+			app.logger.Info("purged deleted words", "count", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Command searchkeys rebuilds the normalized text and search key of every word with the
+// same Go code the API uses. Run it once after migrating to the search_value column, whose
+// SQL backfill only approximates the keys, and again whenever SearchKey changes.
+//
+// Words whose rebuilt key turns out to duplicate another word are left unchanged and listed,
+// and the command exits with status 2. Merge or delete them, then run it again.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"kite-api/internal/data"
+	"log/slog"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	var dsn string
+
+	flag.StringVar(&dsn, "db-dsn", "", "PosgreSQL data source name(DSN)")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	db, err := openDB(dsn)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	defer db.Close()
+
+	start := time.Now()
+
+	updated, conflicts, err := data.WordModel{DB: db}.RebuildSearchKeys()
+
+	for _, conflict := range conflicts {
+		logger.Warn("search key taken, word left unchanged",
+			"id", conflict.ID, "text", conflict.Text, "key", conflict.Key, "held_by", conflict.Holds)
+	}
+
+	if err != nil {
+		logger.Error("rebuilding search keys", "error", err.Error(), "updated", updated, "conflicts", len(conflicts))
+		db.Close()
+		os.Exit(1)
+	}
+
+	logger.Info("rebuilt search keys", "updated", updated, "conflicts", len(conflicts), "duration", time.Since(start).String())
+
+	if len(conflicts) > 0 {
+		db.Close()
+		os.Exit(2)
+	}
+}
+
+func openDB(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -8,6 +8,7 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/tomasen/realip v0.0.0-20180522021738-f0c99a92ddce
 	golang.org/x/crypto v0.28.0
+	golang.org/x/text v0.19.0
 	golang.org/x/time v0.7.0
 )
 
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+// The review states a word or a translation moves through before learners can see it.
+const (
+	StatusDraft     = "draft"
+	StatusSubmitted = "submitted"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+)
+
+// Statuses lists every review state, in the order an entry normally moves through them.
+var Statuses = []string{StatusDraft, StatusSubmitted, StatusApproved, StatusRejected}
+
+// statusTransitions maps each state to the states it may move to next.
+var statusTransitions = map[string][]string{
+	StatusDraft:     {StatusSubmitted},
+	StatusSubmitted: {StatusApproved, StatusRejected},
+	StatusApproved:  {StatusSubmitted},
+	StatusRejected:  {StatusSubmitted},
+}
+
+// CanTransition reports whether an entry in the from state may move to the to state.
+func CanTransition(from, to string) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// Approval records a single status change of a word or a translation.
+// Exactly one of WordID and TranslationID is set.
+type Approval struct {
+	ID            int64     `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	WordID        int64     `json:"word_id,omitempty"`
+	TranslationID int64     `json:"translation_id,omitempty"`
+	ActorID       int64     `json:"actor_id"`
+	FromStatus    string    `json:"from_status"`
+	ToStatus      string    `json:"to_status"`
+	Comment       string    `json:"comment,omitempty"`
+}
+
+type ApprovalModel struct {
+	DB *sql.DB
+
+	// WordIndex is the suggestions index, which only holds approved words
+	WordIndex *WordIndex
+}
+
+// ChangeWordStatus moves the word to approval.ToStatus and records the change,
+// both in one transaction. The word's version guards against concurrent edits.
+// It returns ErrDuplicateWord when approving the word would give two approved words the same search key.
+func (m ApprovalModel) ChangeWordStatus(word *Word, approval *Approval) error {
+	query := `
+		UPDATE words SET status = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	approval.WordID = word.ID
+	approval.FromStatus = word.Status
+
+	err := m.changeStatus(query, []any{approval.ToStatus, word.ID, word.Version}, &word.Version, approval)
+	if err != nil {
+		switch {
+		case isDuplicateWord(err):
+			return ErrDuplicateWord
+		default:
+			return err
+		}
+	}
+
+	word.Status = approval.ToStatus
+	m.WordIndex.Update(word)
+	return nil
+}
+
+// ChangeTranslationStatus moves the translation to approval.ToStatus and records the change,
+// both in one transaction. The translation's version guards against concurrent edits.
+func (m ApprovalModel) ChangeTranslationStatus(translation *Translation, approval *Approval) error {
+	query := `
+		UPDATE translations SET status = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	approval.TranslationID = translation.ID
+	approval.FromStatus = translation.Status
+
+	err := m.changeStatus(query, []any{approval.ToStatus, translation.ID, translation.Version}, &translation.Version, approval)
+	if err != nil {
+		return err
+	}
+
+	translation.Status = approval.ToStatus
+	return nil
+}
+
+func (m ApprovalModel) changeStatus(updateQuery string, updateArgs []any, version *int32, approval *Approval) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, updateQuery, updateArgs...).Scan(version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO approvals (word_id, translation_id, actor_id, from_status, to_status, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	args := []any{nullID(approval.WordID), nullID(approval.TranslationID), approval.ActorID, approval.FromStatus, approval.ToStatus, approval.Comment}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&approval.ID, &approval.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// A status change is a new version of the word, so it goes into the word's history too
+	if approval.WordID != 0 {
+		err = insertWordRevision(ctx, tx, approval.WordID, approval.ActorID, approval.Comment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAllForWord returns the status history of a word, oldest first
+func (m ApprovalModel) GetAllForWord(wordID int64) ([]*Approval, error) {
+	return m.getAll(`word_id = $1`, wordID)
+}
+
+// GetAllForTranslation returns the status history of a translation, oldest first
+func (m ApprovalModel) GetAllForTranslation(translationID int64) ([]*Approval, error) {
+	return m.getAll(`translation_id = $1`, translationID)
+}
+
+func (m ApprovalModel) getAll(condition string, id int64) ([]*Approval, error) {
+	query := `
+		SELECT id, created_at, COALESCE(word_id, 0), COALESCE(translation_id, 0), actor_id, from_status, to_status, comment
+		FROM approvals
+		WHERE ` + condition + `
+		ORDER BY created_at ASC, id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []*Approval{}
+
+	for rows.Next() {
+		var approval Approval
+
+		err := rows.Scan(
+			&approval.ID,
+			&approval.CreatedAt,
+			&approval.WordID,
+			&approval.TranslationID,
+			&approval.ActorID,
+			&approval.FromStatus,
+			&approval.ToStatus,
+			&approval.Comment)
+		if err != nil {
+			return nil, err
+		}
+
+		approvals = append(approvals, &approval)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+// nullID stores a zero ID as NULL, for the optional foreign keys
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
@@ -0,0 +1,190 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"kite-api/internal/validator"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The kinds of problem a learner can report against a word.
+var FeedbackCategories = []string{"wrong_translation", "typo", "offensive", "other"}
+
+// The triage states a piece of feedback moves through.
+const (
+	FeedbackOpen         = "open"
+	FeedbackAcknowledged = "acknowledged"
+	FeedbackResolved     = "resolved"
+)
+
+var FeedbackStatuses = []string{FeedbackOpen, FeedbackAcknowledged, FeedbackResolved}
+
+// feedbackTransitions maps each triage state to the states a moderator may move it to.
+var feedbackTransitions = map[string][]string{
+	FeedbackOpen:         {FeedbackAcknowledged, FeedbackResolved},
+	FeedbackAcknowledged: {FeedbackOpen, FeedbackResolved},
+	FeedbackResolved:     {FeedbackOpen},
+}
+
+// CanTransitionFeedback reports whether feedback in the from state may move to the to state.
+func CanTransitionFeedback(from, to string) bool {
+	return slices.Contains(feedbackTransitions[from], to)
+}
+
+type Feedback struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	WordID    int64     `json:"word_id"`
+	UserID    int64     `json:"user_id"`
+	Category  string    `json:"category"`
+	Message   string    `json:"message"`
+	Status    string    `json:"status"`
+	Version   int32     `json:"-"`
+}
+
+// ValidateFeedback checks if all its fields are provided with valid values
+func ValidateFeedback(v *validator.Validator, feedback *Feedback) {
+	v.Check(validator.IsPermittedValue(feedback.Category, FeedbackCategories), "category", "must be one of: "+strings.Join(FeedbackCategories, ", "))
+
+	v.Check(strings.TrimSpace(feedback.Message) != "", "message", "must be provided")
+	v.Check(utf8.RuneCountInString(feedback.Message) <= 2000, "message", "must be 2000 or less characters")
+
+	v.Check(validator.IsPermittedValue(feedback.Status, FeedbackStatuses), "status", "must be one of: "+strings.Join(FeedbackStatuses, ", "))
+}
+
+type FeedbackModel struct {
+	DB *sql.DB
+}
+
+// Insert a new piece of feedback against a word
+func (m FeedbackModel) Insert(feedback *Feedback) error {
+	query := `
+		INSERT INTO feedbacks (word_id, user_id, category, message, status)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, version`
+
+	args := []any{feedback.WordID, feedback.UserID, feedback.Category, feedback.Message, feedback.Status}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&feedback.ID, &feedback.CreatedAt, &feedback.Version)
+}
+
+// Get retrieves the feedback that matches the id
+func (m FeedbackModel) Get(id int64) (*Feedback, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, word_id, user_id, category, message, status, version
+		FROM feedbacks
+		WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var feedback Feedback
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&feedback.ID,
+		&feedback.CreatedAt,
+		&feedback.WordID,
+		&feedback.UserID,
+		&feedback.Category,
+		&feedback.Message,
+		&feedback.Status,
+		&feedback.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &feedback, nil
+}
+
+// Update the triage status of the feedback, as long as nobody else has changed it since it was read
+func (m FeedbackModel) Update(feedback *Feedback) error {
+	query := `
+		UPDATE feedbacks
+		SET status = $1, version = version + 1
+		WHERE id = $2 AND version = $3
+		RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, feedback.Status, feedback.ID, feedback.Version).Scan(&feedback.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll lists feedback for the triage queue.
+// A zero wordID matches every word, empty status and category match everything.
+func (m FeedbackModel) GetAll(wordID int64, status string, category string, filters Filters) ([]*Feedback, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, word_id, user_id, category, message, status, version
+		FROM feedbacks
+		WHERE (word_id = $1 OR $1 = 0) AND (status = $2 OR $2 = '') AND (category = $3 OR $3 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{wordID, status, category, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	feedbacks := []*Feedback{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var feedback Feedback
+
+		err := rows.Scan(
+			&totalRecords,
+			&feedback.ID,
+			&feedback.CreatedAt,
+			&feedback.WordID,
+			&feedback.UserID,
+			&feedback.Category,
+			&feedback.Message,
+			&feedback.Status,
+			&feedback.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		feedbacks = append(feedbacks, &feedback)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return feedbacks, metadata, nil
+}
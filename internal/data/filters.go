@@ -1,23 +1,37 @@
 package data
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"kite-api/internal/validator"
 	"strings"
 )
 
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type Filters struct {
 	Page         int
 	PageSize     int
 	Sort         string
 	SortSafeList []string
+
+	// UseCursor switches from page numbers to keyset pagination,
+	// where each page starts after the row the Cursor points at.
+	// An empty Cursor asks for the first page.
+	UseCursor bool
+	Cursor    string
 }
 
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size,omitempty"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records,omitempty"`
+	NextCursor   string `json:"next_cursor,omitempty"`
 }
 
 func calculateMetadata(totalRecords, page, pageSize int) Metadata {
@@ -40,6 +54,11 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 1000, "page_size", "must be a no greater than 1_000")
 	v.Check(validator.IsPermittedValue(f.Sort, f.SortSafeList), "sort", "invalid sort value")
+
+	if f.UseCursor {
+		_, err := f.cursor()
+		v.Check(err == nil, "cursor", "must be a next_cursor value returned for the same sort")
+	}
 }
 
 // Check if the sort terms are in the safe list, otherwise, GO PANIC!!
@@ -59,11 +78,100 @@ func (f Filters) sortDirection() string {
 	return "ASC"
 }
 
-// Specify the number of items on a page
+// Specify the number of items on a page.
+// With a cursor one extra row is fetched to find out whether there is a next page.
 func (f Filters) limit() int {
+	if f.UseCursor {
+		return f.PageSize + 1
+	}
 	return f.PageSize
 }
 
 func (f Filters) offset() int {
+	if f.UseCursor {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
+
+// cursor is the position a keyset page starts after: the sort key and id of the last row
+// of the previous page. It is handed to clients as opaque base64 encoded JSON.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+func encodeCursor(sort string, value any, id int64) string {
+	js, _ := json.Marshal(cursor{Sort: sort, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// cursor decodes the Cursor. It returns nil for the first page.
+func (f Filters) cursor() (*cursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+
+	js, err := base64.RawURLEncoding.DecodeString(f.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+
+	var c cursor
+	if err := dec.Decode(&c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// A cursor only makes sense for the order it was created in
+	if c.Sort != f.Sort || c.ID < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	// The sort key has to be of the type of the column it stands for:
+	// none for id, which is in the cursor anyway, a number for relevance
+	// and a string for the text columns
+	var ok bool
+
+	switch strings.TrimPrefix(c.Sort, "-") {
+	case "id":
+		ok = c.Value == nil
+	case "relevance":
+		_, ok = c.Value.(json.Number)
+	default:
+		_, ok = c.Value.(string)
+	}
+
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// keysetCondition builds the condition that selects the rows after the cursor, for the
+// ORDER BY <column> <direction>, id ASC used by the listings. Its placeholders are
+// numbered after the arguments already in args. It returns an empty condition on the first page.
+func (f Filters) keysetCondition(args []any, column, direction string) (string, []any, error) {
+	c, err := f.cursor()
+	if err != nil || c == nil {
+		return "", args, err
+	}
+
+	comparison := ">"
+	if direction == "DESC" {
+		comparison = "<"
+	}
+
+	n := len(args)
+
+	if column == "id" {
+		return fmt.Sprintf("id %s $%d", comparison, n+1), append(args, c.ID), nil
+	}
+
+	condition := fmt.Sprintf("(%[1]s %[2]s $%[3]d OR (%[1]s = $%[3]d AND id > $%[4]d))", column, comparison, n+1, n+2)
+	return condition, append(args, c.Value, c.ID), nil
+}
@@ -3,6 +3,7 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"time"
 )
 
 // ErrRecordNotFound is returned when the specified item is not found.
@@ -13,18 +14,32 @@ var (
 
 // Models contains all the models that are declared and will be passed around as dependency.
 type Models struct {
-	Words       WordModel
-	Tokens      TokenModel
-	Users       UserModel
-	Permissions PermissionModel
+	Approvals    ApprovalModel
+	Feedbacks    FeedbackModel
+	Words        WordModel
+	Tokens       TokenModel
+	Users        UserModel
+	Permissions  PermissionModel
+	Roles        RoleModel
+	Translations TranslationModel
+	Revisions    WordRevisionModel
 }
 
 // NewModels returns an initialised Models to everything.
-func NewModels(db *sql.DB) Models {
+// Users' permissions are cached for permissionsTTL; zero turns the cache off.
+func NewModels(db *sql.DB, permissionsTTL time.Duration) Models {
+	permissionCache := NewPermissionCache(permissionsTTL)
+	wordIndex := NewWordIndex()
+
 	return Models{
-		Words:       WordModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		Users:       UserModel{DB: db},
-		Permissions: PermissionModel{DB: db},
+		Approvals:    ApprovalModel{DB: db, WordIndex: wordIndex},
+		Feedbacks:    FeedbackModel{DB: db},
+		Words:        WordModel{DB: db, Index: wordIndex},
+		Tokens:       TokenModel{DB: db},
+		Users:        UserModel{DB: db},
+		Permissions:  PermissionModel{DB: db, Cache: permissionCache},
+		Roles:        RoleModel{DB: db, Cache: permissionCache},
+		Translations: TranslationModel{DB: db},
+		Revisions:    WordRevisionModel{DB: db},
 	}
 }
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PermissionCache keeps each user's permissions in memory for a while, so
+// guarded routes don't have to query the database on every request. Changes
+// made through PermissionModel and RoleModel invalidate the affected entries;
+// the TTL bounds how stale a change made elsewhere can get.
+//
+// A nil *PermissionCache is valid and caches nothing.
+type PermissionCache struct {
+	ttl time.Duration
+
+	mu         sync.Mutex
+	entries    map[int64]permissionCacheEntry
+	generation uint64
+	sweepAt    int
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type permissionCacheEntry struct {
+	permissions Permissions
+	expires     time.Time
+}
+
+// NewPermissionCache returns a cache that holds entries for ttl.
+// A ttl of zero or less disables caching and returns nil.
+func NewPermissionCache(ttl time.Duration) *PermissionCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &PermissionCache{
+		ttl:     ttl,
+		entries: make(map[int64]permissionCacheEntry),
+		sweepAt: 1024,
+	}
+}
+
+// lookup returns the cached permissions of the user. On a miss it also
+// returns the generation to hand back to store, so that a result read from the
+// database is dropped if an invalidation happened while it was being read.
+func (c *PermissionCache) lookup(userId int64) (Permissions, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userId]
+	if ok && time.Now().Before(entry.expires) {
+		c.hits.Add(1)
+		return entry.permissions, 0, true
+	}
+
+	if ok {
+		delete(c.entries, userId)
+	}
+
+	c.misses.Add(1)
+	return nil, c.generation, false
+}
+
+func (c *PermissionCache) store(userId int64, permissions Permissions, generation uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	now := time.Now()
+
+	// Sweep out expired entries once in a while so users who stop making
+	// requests don't stay in memory forever
+	if len(c.entries) >= c.sweepAt {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+		c.sweepAt = max(1024, 2*len(c.entries))
+	}
+
+	c.entries[userId] = permissionCacheEntry{permissions: permissions, expires: now.Add(c.ttl)}
+}
+
+// Invalidate drops the cached permissions of the given users
+func (c *PermissionCache) Invalidate(userIds ...int64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range userIds {
+		delete(c.entries, id)
+	}
+	c.generation++
+}
+
+// InvalidateAll empties the cache, e.g. after a role every user may hold has changed
+func (c *PermissionCache) InvalidateAll() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.generation++
+}
+
+// Stats reports the number of hits, misses and cached users, for publishing through expvar
+func (c *PermissionCache) Stats() map[string]int64 {
+	if c == nil {
+		return map[string]int64{"hits": 0, "misses": 0, "entries": 0}
+	}
+
+	c.mu.Lock()
+	entries := len(c.entries)
+	c.mu.Unlock()
+
+	return map[string]int64{
+		"hits":    c.hits.Load(),
+		"misses":  c.misses.Load(),
+		"entries": int64(entries),
+	}
+}
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPermissionCacheHitAndMiss(t *testing.T) {
+	c := NewPermissionCache(time.Minute)
+
+	_, generation, ok := c.lookup(1)
+	if ok {
+		t.Fatal("lookup on an empty cache hit")
+	}
+
+	c.store(1, Permissions{"words:read"}, generation)
+
+	permissions, _, ok := c.lookup(1)
+	if !ok {
+		t.Fatal("lookup after store missed")
+	}
+	if !slices.Equal(permissions, Permissions{"words:read"}) {
+		t.Errorf("lookup = %v, want [words:read]", permissions)
+	}
+
+	if _, _, ok := c.lookup(2); ok {
+		t.Error("lookup of another user hit")
+	}
+
+	stats := c.Stats()
+	if stats["hits"] != 1 || stats["misses"] != 2 || stats["entries"] != 1 {
+		t.Errorf("Stats() = %v, want 1 hit, 2 misses and 1 entry", stats)
+	}
+}
+
+func TestPermissionCacheExpiry(t *testing.T) {
+	c := NewPermissionCache(20 * time.Millisecond)
+
+	_, generation, _ := c.lookup(1)
+	c.store(1, Permissions{"words:read"}, generation)
+
+	if _, _, ok := c.lookup(1); !ok {
+		t.Fatal("lookup before the ttl missed")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if _, _, ok := c.lookup(1); ok {
+		t.Fatal("lookup after the ttl hit")
+	}
+
+	if entries := c.Stats()["entries"]; entries != 0 {
+		t.Errorf("expired entry was kept, %d entries", entries)
+	}
+}
+
+func TestPermissionCacheSweep(t *testing.T) {
+	c := NewPermissionCache(20 * time.Millisecond)
+	c.sweepAt = 4
+
+	for id := range int64(4) {
+		_, generation, _ := c.lookup(id)
+		c.store(id, Permissions{"words:read"}, generation)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, generation, _ := c.lookup(100)
+	c.store(100, Permissions{"words:read"}, generation)
+
+	if entries := c.Stats()["entries"]; entries != 1 {
+		t.Errorf("%d entries after a sweep, want 1", entries)
+	}
+}
+
+func TestPermissionCacheInvalidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *PermissionCache)
+		wantKept   bool
+	}{
+		{"invalidate the user", func(c *PermissionCache) { c.Invalidate(1) }, false},
+		{"invalidate several users", func(c *PermissionCache) { c.Invalidate(3, 1) }, false},
+		{"invalidate another user", func(c *PermissionCache) { c.Invalidate(2) }, true},
+		{"invalidate nobody", func(c *PermissionCache) { c.Invalidate() }, true},
+		{"invalidate all", func(c *PermissionCache) { c.InvalidateAll() }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPermissionCache(time.Minute)
+
+			_, generation, _ := c.lookup(1)
+			c.store(1, Permissions{"words:read"}, generation)
+
+			tt.invalidate(c)
+
+			if _, _, ok := c.lookup(1); ok != tt.wantKept {
+				t.Errorf("lookup after invalidating hit = %t, want %t", ok, tt.wantKept)
+			}
+		})
+	}
+}
+
+// A load that started before an invalidation must not put what it read into the
+// cache, since the database may have changed after it was read
+func TestPermissionCacheInvalidateDuringLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *PermissionCache)
+	}{
+		{"invalidate the user", func(c *PermissionCache) { c.Invalidate(1) }},
+		{"invalidate another user", func(c *PermissionCache) { c.Invalidate(2) }},
+		{"invalidate all", func(c *PermissionCache) { c.InvalidateAll() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewPermissionCache(time.Minute)
+
+			_, generation, ok := c.lookup(1)
+			if ok {
+				t.Fatal("lookup on an empty cache hit")
+			}
+
+			tt.invalidate(c)
+			c.store(1, Permissions{"users:admin"}, generation)
+
+			if _, _, ok := c.lookup(1); ok {
+				t.Fatal("stale load was cached")
+			}
+
+			// The next load starts after the invalidation, so it is kept
+			_, generation, _ = c.lookup(1)
+			c.store(1, Permissions{"words:read"}, generation)
+
+			permissions, _, ok := c.lookup(1)
+			if !ok || !slices.Equal(permissions, Permissions{"words:read"}) {
+				t.Errorf("lookup = %v, %t, want [words:read], true", permissions, ok)
+			}
+		})
+	}
+}
+
+func TestPermissionCacheConcurrent(t *testing.T) {
+	c := NewPermissionCache(time.Minute)
+
+	var wg sync.WaitGroup
+
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range 1000 {
+				id := int64(j % 16)
+
+				if _, generation, ok := c.lookup(id); !ok {
+					c.store(id, Permissions{"words:read"}, generation)
+				}
+
+				if j%(i+7) == 0 {
+					c.Invalidate(id)
+				}
+				if j%500 == 0 {
+					c.InvalidateAll()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if entries := c.Stats()["entries"]; entries > 16 {
+		t.Errorf("%d entries for 16 users", entries)
+	}
+}
+
+func TestPermissionCacheNil(t *testing.T) {
+	if c := NewPermissionCache(0); c != nil {
+		t.Fatal("NewPermissionCache(0) is not nil")
+	}
+
+	var c *PermissionCache
+
+	_, _, ok := c.lookup(1)
+	c.store(1, Permissions{"words:read"}, 0)
+	c.Invalidate(1)
+	c.InvalidateAll()
+
+	if ok {
+		t.Error("lookup on a nil cache hit")
+	}
+
+	if _, _, ok := c.lookup(1); ok {
+		t.Error("lookup on a nil cache hit after store")
+	}
+
+	stats := c.Stats()
+	if stats["hits"] != 0 || stats["misses"] != 0 || stats["entries"] != 0 {
+		t.Errorf("Stats() = %v, want all zero", stats)
+	}
+}
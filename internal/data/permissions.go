@@ -5,26 +5,130 @@ import (
 	"database/sql"
 	"github.com/lib/pq"
 	"slices"
+	"strings"
 	"time"
 )
 
 type Permissions []string
 
+// Include reports whether any of the granted permissions covers the code.
+// Grants may use * in place of a segment, so words:* covers words:write,
+// *:read covers every read permission and * on its own covers everything.
 func (p Permissions) Include(code string) bool {
-	return slices.Contains(p, code)
+	return slices.ContainsFunc(p, func(grant string) bool {
+		return permissionMatches(grant, code)
+	})
+}
+
+// IncludeAny reports whether the permissions cover at least one of the codes
+func (p Permissions) IncludeAny(codes ...string) bool {
+	return slices.ContainsFunc(codes, p.Include)
+}
+
+// IncludeAll reports whether the permissions cover every one of the codes.
+// No codes is not a requirement anybody can be said to meet, so it reports false.
+func (p Permissions) IncludeAll(codes ...string) bool {
+	if len(codes) == 0 {
+		return false
+	}
+
+	for _, code := range codes {
+		if !p.Include(code) {
+			return false
+		}
+	}
+	return true
+}
+
+// permissionMatches compares a grant with a required code segment by segment.
+// A * segment matches any single segment, and a trailing * also matches
+// whatever segments follow it, so the hierarchy can grow deeper than resource:action.
+func permissionMatches(grant, code string) bool {
+	if grant == "" || code == "" {
+		return false
+	}
+
+	grantParts := strings.Split(grant, ":")
+	codeParts := strings.Split(code, ":")
+
+	for i, part := range grantParts {
+		if i == len(codeParts) {
+			return false
+		}
+
+		if part == "*" && i == len(grantParts)-1 {
+			return true
+		}
+
+		if part != "*" && part != codeParts[i] {
+			return false
+		}
+	}
+
+	return len(grantParts) == len(codeParts)
 }
 
 type PermissionModel struct {
-	DB *sql.DB
+	DB    *sql.DB
+	Cache *PermissionCache
 }
 
+// GetAllForUser returns the user's permissions: the union of the permissions
+// of their roles and the permissions granted to them directly.
 func (m PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
+	permissions, generation, ok := m.Cache.lookup(userId)
+	if ok {
+		return permissions, nil
+	}
+
 	query := `
 			SELECT permissions.code
 			FROM permissions
 			INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
-			INNER JOIN users ON users_permissions.user_id = users.id
-			WHERE users.id = $1`
+			WHERE users_permissions.user_id = $1
+			UNION
+			SELECT permissions.code
+			FROM permissions
+			INNER JOIN roles_permissions ON roles_permissions.permission_id = permissions.id
+			INNER JOIN users_roles ON users_roles.role_id = roles_permissions.role_id
+			WHERE users_roles.user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	m.Cache.store(userId, permissions, generation)
+
+	return permissions, nil
+}
+
+// GetGrantedToUser returns only the permissions granted to the user directly, not through a role
+func (m PermissionModel) GetGrantedToUser(userId int64) (Permissions, error) {
+	query := `
+		SELECT permissions.code
+		FROM permissions
+		INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+		WHERE users_permissions.user_id = $1`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -58,11 +162,77 @@ func (m PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
 func (m PermissionModel) AddForUser(userId int64, codes ...string) error {
 	query := `
 		INSERT INTO users_permissions
-		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
+		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+		ON CONFLICT DO NOTHING`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	_, err := m.DB.ExecContext(ctx, query, userId, pq.Array(codes))
+	m.Cache.Invalidate(userId)
 	return err
 }
+
+// RemoveForUser takes permissions granted to the user directly away again.
+// Permissions that come from the user's roles are not touched.
+// It returns ErrRecordNotFound if the user had none of them as a direct grant.
+func (m PermissionModel) RemoveForUser(userId int64, codes ...string) error {
+	query := `
+		DELETE FROM users_permissions
+		USING permissions
+		WHERE users_permissions.permission_id = permissions.id
+		AND users_permissions.user_id = $1 AND permissions.code = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userId, pq.Array(codes))
+	m.Cache.Invalidate(userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAll returns every permission code that can be granted
+func (m PermissionModel) GetAll() (Permissions, error) {
+	query := `SELECT code FROM permissions ORDER BY code`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := Permissions{}
+
+	for rows.Next() {
+		var permission string
+
+		err := rows.Scan(&permission)
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,66 @@
+package data
+
+import "testing"
+
+func TestPermissionsInclude(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions Permissions
+		code        string
+		want        bool
+	}{
+		{"exact match", Permissions{"words:read"}, "words:read", true},
+		{"different action", Permissions{"words:read"}, "words:write", false},
+		{"different resource", Permissions{"words:read"}, "translations:read", false},
+		{"no permissions", nil, "words:read", false},
+		{"resource wildcard", Permissions{"words:*"}, "words:write", true},
+		{"resource wildcard other resource", Permissions{"words:*"}, "translations:write", false},
+		{"action wildcard", Permissions{"*:read"}, "feedbacks:read", true},
+		{"action wildcard other action", Permissions{"*:read"}, "feedbacks:write", false},
+		{"everything", Permissions{"*"}, "users:admin", true},
+		{"trailing wildcard covers deeper codes", Permissions{"words:*"}, "words:translations:write", true},
+		{"inner wildcard matches one segment", Permissions{"words:*:write"}, "words:translations:write", true},
+		{"inner wildcard needs the segment", Permissions{"words:*:write"}, "words:write", false},
+		{"grant shorter than code", Permissions{"words"}, "words:read", false},
+		{"grant longer than code", Permissions{"words:read:own"}, "words:read", false},
+		{"wildcard is not a prefix match", Permissions{"word*"}, "words:read", false},
+		{"empty code", Permissions{"*"}, "", false},
+		{"one of several grants", Permissions{"users:admin", "words:*"}, "words:read", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.permissions.Include(tt.code); got != tt.want {
+				t.Errorf("%v.Include(%q) = %t, want %t", tt.permissions, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionsIncludeAnyAll(t *testing.T) {
+	permissions := Permissions{"words:*", "approvals:read"}
+
+	tests := []struct {
+		name    string
+		codes   []string
+		wantAny bool
+		wantAll bool
+	}{
+		{"all covered", []string{"words:write", "approvals:read"}, true, true},
+		{"some covered", []string{"words:write", "approvals:write"}, true, false},
+		{"none covered", []string{"users:admin", "approvals:write"}, false, false},
+		{"single code", []string{"words:read"}, true, true},
+		{"no codes", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := permissions.IncludeAny(tt.codes...); got != tt.wantAny {
+				t.Errorf("IncludeAny(%v) = %t, want %t", tt.codes, got, tt.wantAny)
+			}
+			if got := permissions.IncludeAll(tt.codes...); got != tt.wantAll {
+				t.Errorf("IncludeAll(%v) = %t, want %t", tt.codes, got, tt.wantAll)
+			}
+		})
+	}
+}
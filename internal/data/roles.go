@@ -0,0 +1,273 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"kite-api/internal/validator"
+	"regexp"
+	"slices"
+	"time"
+)
+
+var (
+	ErrDuplicateRole = errors.New("duplicate role")
+
+	roleCodeRX = regexp.MustCompile("^[a-z][a-z0-9_-]{1,49}$")
+)
+
+// Role is a named bundle of permission codes that can be assigned to users
+type Role struct {
+	ID          int64       `json:"id"`
+	Code        string      `json:"code"`
+	Description string      `json:"description"`
+	Permissions Permissions `json:"permissions"`
+	Version     int32       `json:"-"`
+}
+
+// ValidateRole checks the role against the permission codes that exist
+func ValidateRole(v *validator.Validator, role *Role, known Permissions) {
+	v.Check(validator.Matches(role.Code, roleCodeRX), "code", "must be 2-50 lowercase letters, digits, dashes or underscores, starting with a letter")
+	v.Check(len(role.Description) <= 500, "description", "must be 500 or less characters")
+
+	v.Check(validator.Unique(role.Permissions), "permissions", "must not contain duplicate values")
+	for _, code := range role.Permissions {
+		v.Check(slices.Contains(known, code), "permissions", "unknown permission code: "+code)
+	}
+}
+
+type RoleModel struct {
+	DB    *sql.DB
+	Cache *PermissionCache
+}
+
+// GetAll returns every role with its permission codes
+func (m RoleModel) GetAll() ([]*Role, error) {
+	query := `
+		SELECT roles.id, roles.code, roles.description, roles.version,
+		       COALESCE(array_agg(permissions.code ORDER BY permissions.code) FILTER (WHERE permissions.code IS NOT NULL), '{}')
+		FROM roles
+		LEFT JOIN roles_permissions ON roles_permissions.role_id = roles.id
+		LEFT JOIN permissions ON permissions.id = roles_permissions.permission_id
+		GROUP BY roles.id
+		ORDER BY roles.id`
+
+	return m.query(query)
+}
+
+// GetAllForUser returns the roles assigned to the user
+func (m RoleModel) GetAllForUser(userId int64) ([]*Role, error) {
+	query := `
+		SELECT roles.id, roles.code, roles.description, roles.version,
+		       COALESCE(array_agg(permissions.code ORDER BY permissions.code) FILTER (WHERE permissions.code IS NOT NULL), '{}')
+		FROM roles
+		INNER JOIN users_roles ON users_roles.role_id = roles.id
+		LEFT JOIN roles_permissions ON roles_permissions.role_id = roles.id
+		LEFT JOIN permissions ON permissions.id = roles_permissions.permission_id
+		WHERE users_roles.user_id = $1
+		GROUP BY roles.id
+		ORDER BY roles.id`
+
+	return m.query(query, userId)
+}
+
+func (m RoleModel) query(query string, args ...any) ([]*Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*Role{}
+
+	for rows.Next() {
+		var role Role
+
+		err := rows.Scan(&role.ID, &role.Code, &role.Description, &role.Version, pq.Array((*[]string)(&role.Permissions)))
+		if err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, &role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// Get retrieves the role that matches the id
+func (m RoleModel) Get(id int64) (*Role, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT roles.id, roles.code, roles.description, roles.version,
+		       COALESCE(array_agg(permissions.code ORDER BY permissions.code) FILTER (WHERE permissions.code IS NOT NULL), '{}')
+		FROM roles
+		LEFT JOIN roles_permissions ON roles_permissions.role_id = roles.id
+		LEFT JOIN permissions ON permissions.id = roles_permissions.permission_id
+		WHERE roles.id = $1
+		GROUP BY roles.id`
+
+	roles, err := m.query(query, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(roles) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return roles[0], nil
+}
+
+// Insert creates the role together with its permissions
+func (m RoleModel) Insert(role *Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO roles (code, description) VALUES ($1, $2) RETURNING id, version`
+
+	err = tx.QueryRowContext(ctx, query, role.Code, role.Description).Scan(&role.ID, &role.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "roles_code_key"`:
+			return ErrDuplicateRole
+		default:
+			return err
+		}
+	}
+
+	err = setRolePermissions(ctx, tx, role)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update the role and replace its permissions, as long as nobody else has changed it since it was read
+func (m RoleModel) Update(role *Role) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE roles SET code = $1, description = $2, version = version + 1
+		WHERE id = $3 AND version = $4
+		RETURNING version`
+
+	err = tx.QueryRowContext(ctx, query, role.Code, role.Description, role.ID, role.Version).Scan(&role.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "roles_code_key"`:
+			return ErrDuplicateRole
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM roles_permissions WHERE role_id = $1`, role.ID)
+	if err != nil {
+		return err
+	}
+
+	err = setRolePermissions(ctx, tx, role)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	m.Cache.InvalidateAll()
+	return err
+}
+
+func setRolePermissions(ctx context.Context, tx *sql.Tx, role *Role) error {
+	query := `
+		INSERT INTO roles_permissions
+		SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
+
+	_, err := tx.ExecContext(ctx, query, role.ID, pq.Array([]string(role.Permissions)))
+	return err
+}
+
+// Delete the role. Users who had it lose its permissions straight away.
+func (m RoleModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM roles WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	m.Cache.InvalidateAll()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// AddForUser assigns the roles with the given codes to the user
+func (m RoleModel) AddForUser(userId int64, codes ...string) error {
+	query := `
+		INSERT INTO users_roles
+		SELECT $1, roles.id FROM roles WHERE roles.code = ANY($2)
+		ON CONFLICT DO NOTHING`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userId, pq.Array(codes))
+	m.Cache.Invalidate(userId)
+	return err
+}
+
+// RemoveForUser takes the roles with the given codes away from the user
+func (m RoleModel) RemoveForUser(userId int64, codes ...string) error {
+	query := `
+		DELETE FROM users_roles
+		USING roles
+		WHERE users_roles.role_id = roles.id
+		AND users_roles.user_id = $1 AND roles.code = ANY($2)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userId, pq.Array(codes))
+	m.Cache.Invalidate(userId)
+	return err
+}
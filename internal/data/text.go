@@ -0,0 +1,46 @@
+package data
+
+import (
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+// The same Karen or Burmese word can arrive as different sequences of code points:
+// composed or decomposed, or with invisible zero-width characters pasted in from
+// word processors. Text is stored in NFC, and compared through a search key that
+// also folds case and drops the zero-width characters, so those all look alike.
+
+// isZeroWidth reports whether r is one of the invisible characters that creep into pasted text
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff': // zero width space, non-joiner, joiner, word joiner, BOM
+		return true
+	}
+	return false
+}
+
+// NormalizeText puts text in the form it is stored in: NFC, with any whitespace
+// or zero-width characters around it trimmed off.
+func NormalizeText(s string) string {
+	return strings.TrimFunc(norm.NFC.String(s), func(r rune) bool {
+		return unicode.IsSpace(r) || isZeroWidth(r)
+	})
+}
+
+// SearchKey returns the key text is matched and de-duplicated by: the normalized text
+// case folded, without zero-width characters and with runs of whitespace collapsed.
+func SearchKey(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if isZeroWidth(r) {
+			return -1
+		}
+		return r
+	}, NormalizeText(s))
+
+	// A Caser keeps state, so every call gets its own
+	s = cases.Fold().String(s)
+
+	return norm.NFC.String(strings.Join(strings.Fields(s), " "))
+}
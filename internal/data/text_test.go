@@ -0,0 +1,83 @@
+package data
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "kite", "kite"},
+		{"empty", "", ""},
+		{"surrounding whitespace", " \tkite\n ", "kite"},
+		{"no-break space", "\u00a0kite\u00a0", "kite"},
+		{"inner whitespace kept", "big  kite", "big  kite"},
+		{"case kept", "Kite", "Kite"},
+		{"NFD to NFC", "cafe\u0301", "caf\u00e9"},
+		{"NFC unchanged", "caf\u00e9", "caf\u00e9"},
+		{"Myanmar NFD to NFC", "\u1025\u102e", "\u1026"},
+		{"Karen unchanged", "\u1000\u1060\u102f", "\u1000\u1060\u102f"},
+		{"leading byte order mark", "\ufeffkite", "kite"},
+		{"trailing zero width space", "kite\u200b", "kite"},
+		{"zero width characters and spaces around", "\u200c \u2060kite\u200d ", "kite"},
+		{"inner zero width characters kept", "ki\u200bte", "ki\u200bte"},
+		{"only zero width characters", "\u200b\u200c\u200d\u2060\ufeff", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeText(tt.text); got != tt.want {
+				t.Errorf("NormalizeText(%+q) = %+q, want %+q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchKey(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "kite", "kite"},
+		{"empty", "", ""},
+		{"upper case", "KITE", "kite"},
+		{"mixed case", "KiTe", "kite"},
+		{"full case folding", "Stra\u00dfe", "strasse"},
+		{"folding matches expanded form", "STRASSE", "strasse"},
+		{"final sigma", "\u039f\u0394\u039f\u03a3", "\u03bf\u03b4\u03bf\u03c3"},
+		{"final sigma lower case", "\u03bf\u03b4\u03bf\u03c2", "\u03bf\u03b4\u03bf\u03c3"},
+		{"digraph", "\u01c4", "\u01c6"},
+		{"NFD", "Cafe\u0301", "caf\u00e9"},
+		{"NFC", "CAF\u00c9", "caf\u00e9"},
+		{"canonical equivalent", "\u212b", "\u00e5"},
+		{"Myanmar NFD", "\u1025\u102e", "\u1026"},
+		{"Karen", "\u1000\u1060\u102f", "\u1000\u1060\u102f"},
+		{"inner zero width space", "ki\u200bte", "kite"},
+		{"inner zero width joiner", "\u1000\u200d\u1001", "\u1000\u1001"},
+		{"every zero width character", "\u200bk\u200ci\u200dt\u2060e\ufeff", "kite"},
+		{"zero width character between spaces", "big \u200b kite", "big kite"},
+		{"whitespace collapsed", "  big \t\n kite ", "big kite"},
+		{"no-break space collapsed", "big\u00a0\u00a0kite", "big kite"},
+		{"only whitespace and zero width characters", " \u200b\t\ufeff ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SearchKey(tt.text)
+			if got != tt.want {
+				t.Errorf("SearchKey(%+q) = %+q, want %+q", tt.text, got, tt.want)
+			}
+
+			// The key is stored, so it has to survive another round unchanged
+			if again := SearchKey(got); again != got {
+				t.Errorf("SearchKey(%+q) = %+q, not idempotent", got, again)
+			}
+
+			if normalized := SearchKey(NormalizeText(tt.text)); normalized != got {
+				t.Errorf("SearchKey(NormalizeText(%+q)) = %+q, want %+q", tt.text, normalized, got)
+			}
+		})
+	}
+}
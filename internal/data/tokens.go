@@ -1,11 +1,13 @@
 package data
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/base32"
+	"errors"
 	"kite-api/internal/validator"
 	"time"
 )
@@ -13,21 +15,44 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeEmailChange    = "email-change"
+	ScopePasswordReset  = "password-reset"
+	ScopeRefresh        = "refresh"
 )
 
+// ErrTokenReused is returned when a single-use token is presented a second time.
+var ErrTokenReused = errors.New("token reused")
+
 type Token struct {
-	PlainText string    `db:"token"`
-	Hash      []byte    `db:"-"`
-	UserID    int64     `db:"-"`
-	Expiry    time.Time `db:"expiry"`
-	Scope     string    `db:"-"`
+	ID         int64      `db:"id"`
+	PlainText  string     `db:"token"`
+	Hash       []byte     `db:"-"`
+	UserID     int64      `db:"-"`
+	Expiry     time.Time  `db:"expiry"`
+	Scope      string     `db:"-"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	Family     string     `db:"family"`
+}
+
+// NewTokenFamily returns a random identifier for the tokens issued from a single login
+func NewTokenFamily() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes), nil
 }
 
 func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
 	token := &Token{
-		UserID: userID,
-		Expiry: time.Now().Add(ttl),
-		Scope:  scope,
+		UserID:    userID,
+		Expiry:    time.Now().Add(ttl),
+		Scope:     scope,
+		CreatedAt: time.Now(),
 	}
 
 	randomBytes := make([]byte, 16)
@@ -45,6 +70,12 @@ func generateToken(userID int64, ttl time.Duration, scope string) (*Token, error
 	return token, nil
 }
 
+// Matches reports whether tokenPlainText is the plain text this token's hash was made from
+func (t *Token) Matches(tokenPlainText string) bool {
+	hash := sha256.Sum256([]byte(tokenPlainText))
+	return bytes.Equal(t.Hash, hash[:])
+}
+
 func ValidateTokenPlainText(v *validator.Validator, tokenPlainText string) {
 	v.Check(tokenPlainText != "", "token_plain_text", "required")
 	v.Check(len(tokenPlainText) == 26, "token_plain_text", "too_long_or_too_short")
@@ -65,10 +96,23 @@ func (m TokenModel) New(userId int64, ttl time.Duration, scope string) (*Token,
 	return token, err
 }
 
+// NewInFamily creates a token that belongs to the family of tokens issued from one login
+func (m TokenModel) NewInFamily(userId int64, ttl time.Duration, scope string, family string) (*Token, error) {
+	token, err := generateToken(userId, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Family = family
+
+	err = m.Insert(token)
+	return token, err
+}
+
 func (m TokenModel) Insert(token *Token) error {
-	query := `INSERT INTO tokens (user_id, hash, expiry, scope) VALUES ($1, $2, $3, $4)`
+	query := `INSERT INTO tokens (user_id, hash, expiry, scope, created_at, family) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))`
 
-	args := []interface{}{token.UserID, token.Hash, token.Expiry, token.Scope}
+	args := []interface{}{token.UserID, token.Hash, token.Expiry, token.Scope, token.CreatedAt, token.Family}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -87,3 +131,225 @@ func (m TokenModel) DeleteAllForUser(scope string, userId int64) error {
 	_, err := m.DB.ExecContext(ctx, query, userId, scope)
 	return err
 }
+
+// Delete removes a single token, e.g. the bearer token of the current request on logout,
+// together with the rest of its family so the matching refresh token stops working too.
+func (m TokenModel) Delete(scope string, tokenPlainText string) error {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+
+	query := `DELETE FROM tokens WHERE hash = $1 AND scope = $2 RETURNING COALESCE(family, '')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var family string
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], scope).Scan(&family)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if family == "" {
+		return nil
+	}
+
+	return m.DeleteFamily(family)
+}
+
+// DeleteFamilyForUser signs out one of the user's sessions by removing every token of its family.
+// It returns ErrRecordNotFound if the user has no such session.
+func (m TokenModel) DeleteFamilyForUser(userId int64, family string) error {
+	query := `DELETE FROM tokens WHERE family = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, family, userId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteFamily removes every token issued from the same login
+func (m TokenModel) DeleteFamily(family string) error {
+	query := `DELETE FROM tokens WHERE family = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, family)
+	return err
+}
+
+// DeleteOtherSessions revokes the authentication and refresh tokens of every login of the user
+// except the current one, e.g. after a password change, and returns the families it ended.
+// The current login is the family of tokenPlainText, or family when the access token isn't
+// stored, as with a JWT.
+func (m TokenModel) DeleteOtherSessions(userID int64, tokenPlainText string, family string) ([]string, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+
+	query := `
+		WITH deleted AS (
+			DELETE FROM tokens
+			WHERE user_id = $1 AND scope IN ($2, $3) AND hash <> $4
+			AND (family IS NULL OR family <> COALESCE((SELECT family FROM tokens WHERE hash = $4), $5))
+			RETURNING family
+		)
+		SELECT DISTINCT family FROM deleted WHERE family IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, ScopeAuthentication, ScopeRefresh, tokenHash[:], family)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	families := []string{}
+
+	for rows.Next() {
+		var family string
+
+		err := rows.Scan(&family)
+		if err != nil {
+			return nil, err
+		}
+
+		families = append(families, family)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return families, nil
+}
+
+// Consume marks a single-use token as used and returns it.
+// Used tokens are kept until they expire, so that presenting one again can be detected:
+// it revokes the token's whole family and returns ErrTokenReused, because either the
+// owner or whoever stole the token is now holding a copy that should not exist.
+// Along with ErrTokenReused it returns the token with only its Family set, so the
+// caller can revoke what isn't stored, like the JWTs issued from that family.
+func (m TokenModel) Consume(scope string, tokenPlainText string) (*Token, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+
+	query := `
+		UPDATE tokens SET used_at = NOW(), last_used_at = NOW()
+		WHERE hash = $1 AND scope = $2 AND used_at IS NULL AND expiry > $3
+		RETURNING user_id, expiry, created_at, COALESCE(family, '')`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	token := Token{
+		PlainText: tokenPlainText,
+		Hash:      tokenHash[:],
+		Scope:     scope,
+	}
+
+	err := m.DB.QueryRowContext(ctx, query, tokenHash[:], scope, time.Now()).Scan(&token.UserID, &token.Expiry, &token.CreatedAt, &token.Family)
+	if err == nil {
+		return &token, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// The token is unknown, expired or already used. Only the last case is a reuse.
+	query = `SELECT COALESCE(family, '') FROM tokens WHERE hash = $1 AND scope = $2 AND used_at IS NOT NULL`
+
+	var family string
+
+	err = m.DB.QueryRowContext(ctx, query, tokenHash[:], scope).Scan(&family)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if family != "" {
+		err = m.DeleteFamily(family)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Token{Family: family}, ErrTokenReused
+}
+
+// Session is one login of a user: the refresh token family and, for opaque access tokens,
+// the authentication tokens issued from it. It is identified by the family.
+type Session struct {
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Expiry     time.Time  `json:"expiry"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Current    bool       `json:"current"`
+}
+
+// GetSessionsForUser lists the user's sessions that still have an unused, unexpired token,
+// most recently started first. The current session is the family of tokenPlainText,
+// or family when the access token isn't stored, as with a JWT.
+func (m TokenModel) GetSessionsForUser(userId int64, tokenPlainText string, family string) ([]*Session, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+
+	// Used refresh tokens are kept for reuse detection, so they still date the login
+	query := `
+		SELECT family, MIN(created_at), MAX(expiry) FILTER (WHERE used_at IS NULL), MAX(last_used_at),
+			BOOL_OR(hash = $4) OR family = $5
+		FROM tokens
+		WHERE user_id = $1 AND scope IN ($2, $3) AND family IS NOT NULL AND expiry > $6
+		GROUP BY family
+		HAVING BOOL_OR(used_at IS NULL)
+		ORDER BY MIN(created_at) DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userId, ScopeAuthentication, ScopeRefresh, tokenHash[:], family, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(&session.ID, &session.CreatedAt, &session.Expiry, &session.LastUsedAt, &session.Current)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"kite-api/internal/validator"
+	"time"
+	"unicode/utf8"
+)
+
+type Translation struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	WordID    int64     `json:"word_id"`
+	Language  string    `json:"language"`
+	TextValue string    `json:"text"`
+	Notes     string    `json:"notes,omitempty"`
+	AuthorID  int64     `json:"author_id"`
+	Status    string    `json:"status"`
+	Version   int32     `json:"-"`
+}
+
+// ValidateTranslation checks if all its fields are provided with valid values
+func ValidateTranslation(v *validator.Validator, translation *Translation) {
+	v.Check(translation.Language != "", "language", "must be provided")
+	v.Check(validator.Matches(translation.Language, validator.LanguageRX), "language", "must be a valid language tag, e.g. en or ksw")
+
+	text := NormalizeText(translation.TextValue)
+	v.Check(text != "", "text", "translated text must be provided")
+	v.Check(utf8.RuneCountInString(text) <= 255, "text", "translated text must be 255 or less characters")
+
+	v.Check(utf8.RuneCountInString(translation.Notes) <= 1000, "notes", "notes must be 1000 or less characters")
+}
+
+type TranslationModel struct {
+	DB *sql.DB
+}
+
+// Insert a new translation for a word
+func (m TranslationModel) Insert(translation *Translation) error {
+	query := `
+		INSERT INTO translations (word_id, language, text_value, notes, author_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, version`
+
+	// New translations always start as a draft and have to go through review before learners see them
+	if translation.Status == "" {
+		translation.Status = StatusDraft
+	}
+
+	translation.TextValue = NormalizeText(translation.TextValue)
+
+	args := []any{translation.WordID, translation.Language, translation.TextValue, translation.Notes, translation.AuthorID, translation.Status}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&translation.ID, &translation.CreatedAt, &translation.Version)
+}
+
+// Get retrieves the translation that matches the id and belongs to the word
+func (m TranslationModel) Get(wordID, id int64) (*Translation, error) {
+	if wordID < 1 || id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, word_id, language, text_value, notes, author_id, status, version
+		FROM translations
+		WHERE id = $1 AND word_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var translation Translation
+
+	err := m.DB.QueryRowContext(ctx, query, id, wordID).Scan(
+		&translation.ID,
+		&translation.CreatedAt,
+		&translation.WordID,
+		&translation.Language,
+		&translation.TextValue,
+		&translation.Notes,
+		&translation.AuthorID,
+		&translation.Status,
+		&translation.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &translation, nil
+}
+
+// Update the translation, as long as nobody else has changed it since it was read
+func (m TranslationModel) Update(translation *Translation) error {
+	query := `
+		UPDATE translations
+		SET language = $1, text_value = $2, notes = $3, status = $4, version = version + 1
+		WHERE id = $5 AND word_id = $6 AND version = $7
+		RETURNING version`
+
+	translation.TextValue = NormalizeText(translation.TextValue)
+
+	args := []any{translation.Language, translation.TextValue, translation.Notes, translation.Status, translation.ID, translation.WordID, translation.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&translation.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete the translation that matches the id and belongs to the word
+func (m TranslationModel) Delete(wordID, id int64) error {
+	if wordID < 1 || id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM translations WHERE id = $1 AND word_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, wordID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAllForWord lists the translations of a word, optionally narrowed down to one language.
+// An empty statuses slice matches translations in any review state.
+func (m TranslationModel) GetAllForWord(wordID int64, language string, statuses []string, filters Filters) ([]*Translation, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, word_id, language, text_value, notes, author_id, status, version
+		FROM translations
+		WHERE word_id = $1 AND (LOWER(language) = LOWER($2) OR $2 = '')
+		AND (status = ANY($3) OR cardinality($3::text[]) = 0)
+		ORDER BY %s %s, id ASC
+		LIMIT $4 OFFSET $5`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{wordID, language, pq.Array(statuses), filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	translations := []*Translation{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var translation Translation
+
+		err := rows.Scan(
+			&totalRecords,
+			&translation.ID,
+			&translation.CreatedAt,
+			&translation.WordID,
+			&translation.Language,
+			&translation.TextValue,
+			&translation.Notes,
+			&translation.AuthorID,
+			&translation.Status,
+			&translation.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		translations = append(translations, &translation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return translations, metadata, nil
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"fmt"
 	"golang.org/x/crypto/bcrypt"
 	"kite-api/internal/validator"
 	"time"
@@ -17,13 +18,15 @@ var (
 var AnonymousUser = &User{}
 
 type User struct {
-	ID        int64     `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  password  `json:"-"`
-	Activated bool      `json:"activated"`
-	Version   int       `json:"-"`
+	ID           int64      `json:"id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Name         string     `json:"name"`
+	Email        string     `json:"email"`
+	Password     password   `json:"-"`
+	Activated    bool       `json:"activated"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty"`   // set when an administrator locks the account
+	PendingEmail *string    `json:"pending_email,omitempty"` // a new address waiting to be confirmed
+	Version      int        `json:"-"`
 }
 
 type password struct {
@@ -39,6 +42,11 @@ func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+// IsDisabled reports whether an administrator has locked the user out
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
 func (p *password) Set(plaintextPassword string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
 	if err != nil {
@@ -112,9 +120,46 @@ func (m UserModel) Insert(user *User) error {
 	return nil
 }
 
+// Get retrieves the user that matches the id
+func (m UserModel) Get(id int64) (*User, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT id, created_at, name, email, activated, disabled_at, pending_email, password_hash, version
+		FROM users WHERE id = $1`
+
+	var user User
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&user.ID,
+		&user.CreatedAt,
+		&user.Name,
+		&user.Email,
+		&user.Activated,
+		&user.DisabledAt,
+		&user.PendingEmail,
+		&user.Password.hash,
+		&user.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, created_at, name, email, activated, password_hash, version 
+		SELECT id, created_at, name, email, activated, disabled_at, pending_email, password_hash, version 
 		FROM users WHERE email = $1`
 
 	var user User
@@ -127,6 +172,8 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Name,
 		&user.Email,
 		&user.Activated,
+		&user.DisabledAt,
+		&user.PendingEmail,
 		&user.Password.hash,
 		&user.Version)
 
@@ -146,11 +193,11 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 func (m UserModel) Update(user *User) error {
 	query := `
 		UPDATE users
-		SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1
-		WHERE id = $5 AND version = $6
+		SET name = $1, email = $2, password_hash = $3, activated = $4, disabled_at = $5, pending_email = $6, version = version + 1
+		WHERE id = $7 AND version = $8
 		RETURNING version`
 
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.ID, user.Version}
+	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.DisabledAt, user.PendingEmail, user.ID, user.Version}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -172,14 +219,24 @@ func (m UserModel) Update(user *User) error {
 func (m UserModel) GetForToken(tokenScope, tokenPlainText string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlainText))
 
+	// Looking an authentication token up also records when it was last used, so users can
+	// review their sessions. To the minute is plenty for that, so most requests write nothing.
 	query := `
+		WITH found_token AS (
+			SELECT hash, user_id, last_used_at FROM tokens
+			WHERE hash = $1 AND scope = $2 AND expiry > $3
+		), touched_token AS (
+			UPDATE tokens SET last_used_at = NOW()
+			FROM found_token
+			WHERE tokens.hash = found_token.hash AND $2 = $4
+			AND (found_token.last_used_at IS NULL OR found_token.last_used_at < NOW() - INTERVAL '1 minute')
+		)
 		SELECT users.id, users.created_at, users.name, users.email, 
-		       users.activated, users.password_hash, users.version 
+		       users.activated, users.disabled_at, users.pending_email, users.password_hash, users.version 
 		FROM users 
-		INNER JOIN tokens ON users.id = tokens.user_id
-		WHERE tokens.hash = $1 AND tokens.scope = $2 AND tokens.expiry > $3`
+		INNER JOIN found_token ON users.id = found_token.user_id`
 
-	args := []any{tokenHash[:], tokenScope, time.Now()}
+	args := []any{tokenHash[:], tokenScope, time.Now(), ScopeAuthentication}
 
 	var user User
 
@@ -192,6 +249,8 @@ func (m UserModel) GetForToken(tokenScope, tokenPlainText string) (*User, error)
 		&user.Name,
 		&user.Email,
 		&user.Activated,
+		&user.DisabledAt,
+		&user.PendingEmail,
 		&user.Password.hash,
 		&user.Version)
 
@@ -206,3 +265,83 @@ func (m UserModel) GetForToken(tokenScope, tokenPlainText string) (*User, error)
 
 	return &user, nil
 }
+
+// GetAll lists users, optionally narrowed down by name and email
+func (m UserModel) GetAll(name string, email string, filters Filters) ([]*User, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT count(*) OVER(), id, created_at, name, email, activated, disabled_at, pending_email, password_hash, version
+		FROM users
+		WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '') AND (email = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{name, email, filters.limit(), filters.offset()}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	totalRecords := 0
+
+	for rows.Next() {
+		var user User
+
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Email,
+			&user.Activated,
+			&user.DisabledAt,
+			&user.PendingEmail,
+			&user.Password.hash,
+			&user.Version)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return users, metadata, nil
+}
+
+// Delete removes the user. Their tokens and permissions go with them through ON DELETE CASCADE.
+func (m UserModel) Delete(id int64) error {
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+
+	query := `DELETE FROM users WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WordIndex is an in-memory prefix index of the approved words, for typeahead suggestions.
+// It is a slice sorted by search key, so a prefix lookup is a binary search followed
+// by a short scan. WordModel and ApprovalModel keep it up to date as words change, and it
+// is reloaded from the database from time to time to pick up changes made elsewhere.
+//
+// A nil *WordIndex is valid and indexes nothing.
+type WordIndex struct {
+	mu      sync.RWMutex
+	entries []wordIndexEntry
+	keys    map[int64]string // id -> key, to find an entry again when it changes
+
+	ready   bool
+	loading bool
+	pending []wordIndexChange // changes made while a load was reading the database
+}
+
+type wordIndexEntry struct {
+	key  string
+	text string
+	id   int64
+}
+
+type wordIndexChange struct {
+	word    *Word
+	removed bool
+}
+
+// NewWordIndex returns an empty index. It reports that it isn't ready until Load has run.
+func NewWordIndex() *WordIndex {
+	return &WordIndex{keys: make(map[int64]string)}
+}
+
+func compareEntries(a, b wordIndexEntry) int {
+	return cmp.Or(strings.Compare(a.key, b.key), cmp.Compare(a.id, b.id))
+}
+
+// Ready reports whether the index has been loaded and can answer lookups on its own
+func (x *WordIndex) Ready() bool {
+	if x == nil {
+		return false
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return x.ready
+}
+
+// Load replaces the contents of the index with the approved words in the database.
+// Changes made while it reads are applied on top, so none are lost.
+func (x *WordIndex) Load(db *sql.DB) error {
+	if x == nil {
+		return nil
+	}
+
+	x.mu.Lock()
+	x.loading = true
+	x.pending = nil
+	x.mu.Unlock()
+
+	entries, err := x.read(db)
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.loading = false
+	pending := x.pending
+	x.pending = nil
+
+	if err != nil {
+		return err
+	}
+
+	slices.SortFunc(entries, compareEntries)
+
+	x.entries = entries
+	x.keys = make(map[int64]string, len(entries))
+	for _, entry := range entries {
+		x.keys[entry.id] = entry.key
+	}
+
+	for _, change := range pending {
+		x.apply(change)
+	}
+
+	x.ready = true
+	return nil
+}
+
+func (x *WordIndex) read(db *sql.DB) ([]wordIndexEntry, error) {
+	query := `SELECT id, text_value FROM words WHERE status = $1 AND deleted_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, StatusApproved)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []wordIndexEntry
+
+	for rows.Next() {
+		var entry wordIndexEntry
+
+		err := rows.Scan(&entry.id, &entry.text)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.key = SearchKey(entry.text)
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Update brings the index in line with the word: approved words are indexed,
+// anything else is taken out.
+func (x *WordIndex) Update(word *Word) {
+	x.change(wordIndexChange{word: word, removed: word.Status != StatusApproved || word.DeletedAt != nil})
+}
+
+// Remove takes the word with the id out of the index
+func (x *WordIndex) Remove(id int64) {
+	x.change(wordIndexChange{word: &Word{ID: id}, removed: true})
+}
+
+func (x *WordIndex) change(change wordIndexChange) {
+	if x == nil {
+		return
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.loading {
+		x.pending = append(x.pending, change)
+	}
+
+	x.apply(change)
+}
+
+func (x *WordIndex) apply(change wordIndexChange) {
+	id := change.word.ID
+
+	if key, ok := x.keys[id]; ok {
+		i, found := slices.BinarySearchFunc(x.entries, wordIndexEntry{key: key, id: id}, compareEntries)
+		if found {
+			x.entries = slices.Delete(x.entries, i, i+1)
+		}
+		delete(x.keys, id)
+	}
+
+	if change.removed {
+		return
+	}
+
+	entry := wordIndexEntry{key: SearchKey(change.word.TextValue), text: change.word.TextValue, id: id}
+
+	i, _ := slices.BinarySearchFunc(x.entries, entry, compareEntries)
+	x.entries = slices.Insert(x.entries, i, entry)
+	x.keys[id] = entry.key
+}
+
+// Suggest returns up to limit distinct word texts whose search key starts with the
+// search key of prefix, in order of their keys.
+func (x *WordIndex) Suggest(prefix string, limit int) []string {
+	suggestions := []string{}
+
+	if x == nil {
+		return suggestions
+	}
+
+	key := SearchKey(prefix)
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	i, _ := slices.BinarySearchFunc(x.entries, wordIndexEntry{key: key}, compareEntries)
+
+	// Words with the same key are ordered by id, not text, so equal texts need not be adjacent
+	seen := make(map[string]bool)
+
+	for ; i < len(x.entries) && len(suggestions) < limit; i++ {
+		entry := x.entries[i]
+
+		if !strings.HasPrefix(entry.key, key) {
+			break
+		}
+
+		if seen[entry.text] {
+			continue
+		}
+		seen[entry.text] = true
+
+		suggestions = append(suggestions, entry.text)
+	}
+
+	return suggestions
+}
@@ -0,0 +1,185 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"slices"
+	"time"
+)
+
+// WordRevision is a snapshot of a word as it was at one version
+type WordRevision struct {
+	WordID       int64     `json:"word_id"`
+	Version      int32     `json:"version"`
+	CreatedAt    time.Time `json:"created_at"`
+	TextValue    string    `json:"text"`
+	Difficulty   string    `json:"difficulty"`
+	RelatedWords []string  `json:"related_words"`
+	Status       string    `json:"status"`
+	EditorID     int64     `json:"editor_id"`
+	Reason       string    `json:"reason"`
+}
+
+// FieldChange is the before and after value of a field that differs between two revisions
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// Diff lists the fields that changed from the previous revision to this one,
+// keyed by their JSON names. A nil previous revision means the word was just created,
+// so every field counts as changed.
+func (r *WordRevision) Diff(previous *WordRevision) map[string]FieldChange {
+	if previous == nil {
+		previous = &WordRevision{RelatedWords: []string{}}
+	}
+
+	changes := make(map[string]FieldChange)
+
+	if r.TextValue != previous.TextValue {
+		changes["text"] = FieldChange{From: previous.TextValue, To: r.TextValue}
+	}
+
+	if r.Difficulty != previous.Difficulty {
+		changes["difficulty"] = FieldChange{From: previous.Difficulty, To: r.Difficulty}
+	}
+
+	if !slices.Equal(r.RelatedWords, previous.RelatedWords) {
+		changes["related_words"] = FieldChange{From: previous.RelatedWords, To: r.RelatedWords}
+	}
+
+	if r.Status != previous.Status {
+		changes["status"] = FieldChange{From: previous.Status, To: r.Status}
+	}
+
+	return changes
+}
+
+// insertWordRevision snapshots the word as it stands inside tx, so the
+// revision is only kept if the change that produced it is committed.
+func insertWordRevision(ctx context.Context, tx *sql.Tx, wordID int64, editorID int64, reason string) error {
+	query := `
+		INSERT INTO word_revisions (word_id, version, text_value, difficulty, related_words, status, editor_id, reason)
+		SELECT id, version, text_value, difficulty, related_words, status, $2, $3
+		FROM words
+		WHERE id = $1`
+
+	_, err := tx.ExecContext(ctx, query, wordID, editorID, reason)
+	return err
+}
+
+type WordRevisionModel struct {
+	DB *sql.DB
+}
+
+// GetAllForWord lists the revisions of a word in the given review states, newest first.
+// An empty statuses slice lists revisions in any state.
+func (m WordRevisionModel) GetAllForWord(wordID int64, statuses []string) ([]*WordRevision, error) {
+	query := `
+		SELECT word_id, version, created_at, text_value, difficulty, related_words, status, editor_id, reason
+		FROM word_revisions
+		WHERE word_id = $1 AND (status = ANY($2) OR cardinality($2::text[]) = 0)
+		ORDER BY version DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, wordID, pq.Array(statuses))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*WordRevision{}
+
+	for rows.Next() {
+		var revision WordRevision
+
+		err := rows.Scan(
+			&revision.WordID,
+			&revision.Version,
+			&revision.CreatedAt,
+			&revision.TextValue,
+			&revision.Difficulty,
+			pq.Array(&revision.RelatedWords),
+			&revision.Status,
+			&revision.EditorID,
+			&revision.Reason)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Get retrieves the revision of the word at the given version
+func (m WordRevisionModel) Get(wordID int64, version int32) (*WordRevision, error) {
+	if version < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `
+		SELECT word_id, version, created_at, text_value, difficulty, related_words, status, editor_id, reason
+		FROM word_revisions
+		WHERE word_id = $1 AND version = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var revision WordRevision
+
+	err := m.DB.QueryRowContext(ctx, query, wordID, version).Scan(
+		&revision.WordID,
+		&revision.Version,
+		&revision.CreatedAt,
+		&revision.TextValue,
+		&revision.Difficulty,
+		pq.Array(&revision.RelatedWords),
+		&revision.Status,
+		&revision.EditorID,
+		&revision.Reason)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
+
+// GetPrevious retrieves the latest revision in one of the given review states that came
+// before the given version, if there is one. An empty statuses slice matches any state.
+func (m WordRevisionModel) GetPrevious(wordID int64, version int32, statuses []string) (*WordRevision, error) {
+	query := `
+		SELECT max(version) FROM word_revisions
+		WHERE word_id = $1 AND version < $2 AND (status = ANY($3) OR cardinality($3::text[]) = 0)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var previous sql.NullInt32
+
+	err := m.DB.QueryRowContext(ctx, query, wordID, version, pq.Array(statuses)).Scan(&previous)
+	if err != nil {
+		return nil, err
+	}
+
+	if !previous.Valid {
+		return nil, ErrRecordNotFound
+	}
+
+	return m.Get(wordID, previous.Int32)
+}
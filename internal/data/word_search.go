@@ -0,0 +1,178 @@
+package data
+
+import (
+	"fmt"
+	"github.com/lib/pq"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// How the related words of a search are matched
+const (
+	MatchAll = "all" // the word is related to every one of them
+	MatchAny = "any" // the word is related to at least one of them
+)
+
+// How the text of a search is matched against the words
+const (
+	SearchFullText = "fulltext" // whole words, ranked with ts_rank
+	SearchPrefix   = "prefix"   // words starting with the text, so "kit" finds "kite"
+	SearchFuzzy    = "fuzzy"    // trigram similarity, which forgives misspellings
+)
+
+var SearchModes = []string{SearchFullText, SearchPrefix, SearchFuzzy}
+
+// WordSearch holds the search terms shared by the word listing and the export.
+// An empty field matches every word, and an empty Statuses slice matches words in any review state.
+type WordSearch struct {
+	Text         string
+	Mode         string
+	Difficulty   string
+	Statuses     []string
+	RelatedWords []string
+	Match        string
+}
+
+// where builds the WHERE clause for the search, numbering its placeholders after the
+// arguments already in args, and returns it with args extended by its own values.
+// It also returns the SQL expression for the relevance score of a word, which is
+// zero when there is no text to search for.
+// The text is matched by its search key against the words' search_value, so neither
+// case nor the Unicode form it was typed in matter.
+func (s WordSearch) where(args []any) (string, string, []any) {
+	n := len(args)
+	key := SearchKey(s.Text)
+
+	var text, score string
+
+	switch s.Mode {
+	case SearchPrefix:
+		// The pattern has its own placeholder, after the fixed ones below
+		text = fmt.Sprintf(`($%[1]d = '' OR search_value LIKE $%[2]d)`, n+1, n+4)
+		score = fmt.Sprintf(`similarity(search_value, $%d)::float8`, n+1)
+	case SearchFuzzy:
+		// % is pg_trgm's similarity operator, true above pg_trgm.similarity_threshold
+		text = fmt.Sprintf(`($%[1]d = '' OR search_value %% $%[1]d)`, n+1)
+		score = fmt.Sprintf(`similarity(search_value, $%d)::float8`, n+1)
+	default:
+		// to_tsvector extracts the words out and disregards all the rest like punctuation and symbols.
+		// plainto_tsquery extracts words out from the search terms.
+		// @@ matches the left side and the right side.
+		text = fmt.Sprintf(`(to_tsvector('simple', search_value) @@ plainto_tsquery('simple', $%[1]d) OR $%[1]d = '')`, n+1)
+		score = fmt.Sprintf(`ts_rank(to_tsvector('simple', search_value), plainto_tsquery('simple', $%d))::float8`, n+1)
+	}
+
+	if key == "" {
+		score = "0::float8"
+	}
+
+	clause := fmt.Sprintf(`WHERE %[1]s
+AND (LOWER(difficulty) = LOWER($%[2]d) OR $%[2]d = '')
+AND (status = ANY($%[3]d) OR cardinality($%[3]d::text[]) = 0) AND deleted_at IS NULL`, text, n+2, n+3)
+
+	args = append(args, key, s.Difficulty, pq.Array(s.Statuses))
+
+	if s.Mode == SearchPrefix {
+		args = append(args, escapeLike(key)+"%")
+	}
+
+	// Only filter on related words when asked to, so the GIN index can serve the query:
+	// @> (contains) when every word has to match, && (overlaps) when any of them will do
+	if len(s.RelatedWords) > 0 {
+		operator := "@>"
+		if s.Match == MatchAny {
+			operator = "&&"
+		}
+
+		related := make([]string, len(s.RelatedWords))
+		for i, word := range s.RelatedWords {
+			related[i] = NormalizeText(word)
+		}
+
+		clause += fmt.Sprintf(" AND related_words %s $%d", operator, len(args)+1)
+		args = append(args, pq.Array(related))
+	}
+
+	return clause, score, args
+}
+
+// orderBy returns the expression and direction to sort by.
+// Sorting by relevance puts the best matches first, so -relevance puts them last.
+func (s WordSearch) orderBy(filters Filters, score string) (string, string) {
+	column, direction := filters.sortColumn(), filters.sortDirection()
+
+	if column != "relevance" {
+		return column, direction
+	}
+
+	if direction == "ASC" {
+		return score, "DESC"
+	}
+	return score, "ASC"
+}
+
+// escapeLike escapes the characters that have a special meaning in a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// highlight returns the word text, HTML escaped, with the parts that matched the search
+// wrapped in <mark> tags. A prefix search marks the matching start of the text, the other
+// modes mark every occurrence of each search term. A fuzzy match may have no part in common
+// with the search text, in which case nothing is marked.
+func (s WordSearch) highlight(text string) string {
+	terms := strings.Fields(NormalizeText(s.Text))
+	if s.Mode == SearchPrefix {
+		terms = []string{NormalizeText(s.Text)}
+	}
+
+	// marked[i] is set for every byte of text that falls inside a match
+	marked := make([]bool, len(text))
+
+	for _, term := range terms {
+		termLength := utf8.RuneCountInString(term)
+
+		for i := range text {
+			if !utf8.RuneStart(text[i]) {
+				continue
+			}
+
+			// Compare a window of the same number of runes, ignoring case
+			end := i
+			for count := 0; count < termLength && end < len(text); count++ {
+				_, size := utf8.DecodeRuneInString(text[end:])
+				end += size
+			}
+
+			if strings.EqualFold(text[i:end], term) {
+				for j := i; j < end; j++ {
+					marked[j] = true
+				}
+			}
+
+			if s.Mode == SearchPrefix {
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+
+		i = j
+	}
+
+	return b.String()
+}
@@ -8,26 +8,45 @@ import (
 	"fmt"
 	"github.com/lib/pq"
 	"kite-api/internal/validator"
-	"strings"
 	"time"
+	"unicode/utf8"
 
 	_ "github.com/lib/pq"
 )
 
+var ErrDuplicateWord = errors.New("duplicate word")
+
+// isDuplicateWord reports whether err comes from one of the unique indexes on search_value:
+// one approved word per search key, and one live word per search key for each user
+func isDuplicateWord(err error) bool {
+	switch err.Error() {
+	case `pq: duplicate key value violates unique constraint "words_search_value_approved_key"`,
+		`pq: duplicate key value violates unique constraint "words_user_id_search_value_key"`:
+		return true
+	}
+	return false
+}
+
 type Word struct {
-	ID           int64     `json:"id"`
-	CreatedAt    time.Time `json:"-"`
-	TextValue    string    `json:"text"`
-	Difficulty   string    `json:"difficulty"`
-	RelatedWords []string  `json:"related_words,omitempty"`
-	UserId       int64     `json:"user_id,omitempty"`
-	Version      int32     `json:"-"`
+	ID           int64      `json:"id"`
+	CreatedAt    time.Time  `json:"-"`
+	TextValue    string     `json:"text"`
+	Difficulty   string     `json:"difficulty"`
+	RelatedWords []string   `json:"related_words,omitempty"`
+	UserId       int64      `json:"user_id,omitempty"`
+	Status       string     `json:"status"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	Score        float64    `json:"score,omitempty"`     // how well the word matched a text search
+	Highlight    string     `json:"highlight,omitempty"` // the text with the matched parts wrapped in <mark>
+	Version      int32      `json:"-"`
 }
 
 // ValidateWord checks if all its fields are provided with valid values
 func ValidateWord(v *validator.Validator, word *Word) {
-	v.Check(strings.Trim(word.TextValue, " ") != "", "text", "word text must be provided")
-	v.Check(len(word.TextValue) <= 255, "title", "word text must be 255 or less characters")
+	// Count characters rather than bytes, since a Karen character takes three bytes in UTF-8
+	text := NormalizeText(word.TextValue)
+	v.Check(text != "", "text", "word text must be provided")
+	v.Check(utf8.RuneCountInString(text) <= 255, "text", "word text must be 255 or less characters")
 
 	difficulties := []string{"easy", "medium", "hard"}
 
@@ -56,6 +75,15 @@ func (w Word) MarshalJSON() ([]byte, error) {
 	return json.Marshal(aux)
 }
 
+// normalize puts the word's text and related words in the form they are stored in
+func (w *Word) normalize() {
+	w.TextValue = NormalizeText(w.TextValue)
+
+	for i, related := range w.RelatedWords {
+		w.RelatedWords[i] = NormalizeText(related)
+	}
+}
+
 func includes(values []string, v string) bool {
 	for _, w := range values {
 		if w == v {
@@ -67,17 +95,193 @@ func includes(values []string, v string) bool {
 
 type WordModel struct {
 	DB *sql.DB
+
+	// Index is kept in step with every change made through the model, for suggestions
+	Index *WordIndex
 }
 
-// Insert a new entry of word or text
+// Insert a new entry of word or text.
+// It returns ErrDuplicateWord if the search key is taken by an approved word or by another of
+// the user's own words. Other users' drafts don't count, so their existence isn't given away.
 func (w WordModel) Insert(word *Word) error {
-
-	query := `INSERT INTO words (text_value, difficulty, related_words, user_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+	query := `
+		INSERT INTO words (text_value, search_value, difficulty, related_words, user_id, status)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE NOT EXISTS (SELECT 1 FROM words WHERE search_value = $2 AND status = $7 AND deleted_at IS NULL)
+		RETURNING id, created_at, version`
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{word.TextValue, word.Difficulty, pq.Array(word.RelatedWords), word.UserId}
-	return w.DB.QueryRowContext(ctx, query, args...).Scan(&word.ID, &word.CreatedAt)
+	// New words always start as a draft and have to go through review before learners see them
+	if word.Status == "" {
+		word.Status = StatusDraft
+	}
+
+	word.normalize()
+
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := []any{word.TextValue, SearchKey(word.TextValue), word.Difficulty, pq.Array(word.RelatedWords), word.UserId, word.Status, StatusApproved}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&word.ID, &word.CreatedAt, &word.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows), isDuplicateWord(err):
+			return ErrDuplicateWord
+		default:
+			return err
+		}
+	}
+
+	// The first revision records who created the word
+	err = insertWordRevision(ctx, tx, word.ID, word.UserId, "")
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	w.Index.Update(word)
+	return nil
+}
+
+// InsertBatch inserts the words in a single transaction, skipping any that Insert would
+// reject as duplicates. It reports which words were created.
+// With dryRun set nothing is written; the report shows what would happen.
+func (w WordModel) InsertBatch(words []*Word, dryRun bool) ([]bool, error) {
+	if dryRun {
+		return w.checkBatch(words)
+	}
+
+	// A conflict has to be skipped in the statement itself, since an error would abort the transaction
+	query := `
+		INSERT INTO words (text_value, search_value, difficulty, related_words, user_id, status)
+		SELECT $1, $2, $3, $4, $5, $6
+		WHERE NOT EXISTS (SELECT 1 FROM words WHERE search_value = $2 AND status = $7 AND deleted_at IS NULL)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at, version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]bool, len(words))
+
+	for i, word := range words {
+		if word.Status == "" {
+			word.Status = StatusDraft
+		}
+
+		word.normalize()
+
+		args := []any{word.TextValue, SearchKey(word.TextValue), word.Difficulty, pq.Array(word.RelatedWords), word.UserId, word.Status, StatusApproved}
+
+		err := tx.QueryRowContext(ctx, query, args...).Scan(&word.ID, &word.CreatedAt, &word.Version)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				continue
+			default:
+				return nil, err
+			}
+		}
+
+		err = insertWordRevision(ctx, tx, word.ID, word.UserId, "imported")
+		if err != nil {
+			return nil, err
+		}
+
+		created[i] = true
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, word := range words {
+		if created[i] {
+			w.Index.Update(word)
+		}
+	}
+
+	return created, nil
+}
+
+// checkBatch reports which of the words InsertBatch would create, by looking their
+// search keys up rather than inserting them
+func (w WordModel) checkBatch(words []*Word) ([]bool, error) {
+	query := `
+		SELECT search_value, user_id, status FROM words
+		WHERE search_value = ANY($1) AND deleted_at IS NULL AND (status = $2 OR user_id = ANY($3))`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	type userKey struct {
+		userID int64
+		key    string
+	}
+
+	keys := make([]string, len(words))
+	users := make([]int64, len(words))
+	for i, word := range words {
+		word.normalize()
+		keys[i] = SearchKey(word.TextValue)
+		users[i] = word.UserId
+	}
+
+	rows, err := w.DB.QueryContext(ctx, query, pq.Array(keys), StatusApproved, pq.Array(users))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approved := make(map[string]bool)
+	owned := make(map[userKey]bool)
+
+	for rows.Next() {
+		var (
+			key    string
+			userID int64
+			status string
+		)
+
+		err := rows.Scan(&key, &userID, &status)
+		if err != nil {
+			return nil, err
+		}
+
+		if status == StatusApproved {
+			approved[key] = true
+		}
+		owned[userKey{userID, key}] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A word earlier in the batch takes its key for its owner just as an existing one does
+	created := make([]bool, len(words))
+	for i, key := range keys {
+		uk := userKey{users[i], key}
+		created[i] = !approved[key] && !owned[uk]
+		owned[uk] = true
+	}
+
+	return created, nil
 }
 
 // Get retrieves a record that matches the id
@@ -86,13 +290,13 @@ func (w WordModel) Get(id int64) (*Word, error) {
 		return nil, ErrRecordNotFound
 	}
 
-	query := `SELECT id, text_value, difficulty, related_words, user_id, created_at, version FROM words WHERE id = $1`
+	query := `SELECT id, text_value, difficulty, related_words, user_id, status, created_at, version FROM words WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var word Word
-	err := w.DB.QueryRowContext(ctx, query, id).Scan(&word.ID, &word.TextValue, &word.Difficulty, pq.Array(&word.RelatedWords), &word.UserId, &word.CreatedAt, &word.Version)
+	err := w.DB.QueryRowContext(ctx, query, id).Scan(&word.ID, &word.TextValue, &word.Difficulty, pq.Array(&word.RelatedWords), &word.UserId, &word.Status, &word.CreatedAt, &word.Version)
 
 	if err != nil {
 		switch {
@@ -105,29 +309,74 @@ func (w WordModel) Get(id int64) (*Word, error) {
 	return &word, nil
 }
 
-// Update the word
-func (w WordModel) Update(word *Word) error {
-	query := `UPDATE words SET text_value = $1, difficulty = $2, related_words = $3, user_id = $4, version = version + 1 WHERE id = $5 AND version = $6 RETURNING version`
-	args := []any{word.TextValue, word.Difficulty, pq.Array(word.RelatedWords), word.UserId, word.ID, word.Version}
+// Update the word and record the new version in its revision history, in one transaction.
+// The owner is set when the word is created and never changes.
+// It returns ErrDuplicateWord if the new search key is taken, as Insert does.
+func (w WordModel) Update(word *Word, editorID int64, reason string) error {
+	word.normalize()
+	key := SearchKey(word.TextValue)
+
+	query := `UPDATE words SET text_value = $1, search_value = $2, difficulty = $3, related_words = $4, status = $5, version = version + 1 WHERE id = $6 AND version = $7 AND deleted_at IS NULL RETURNING version`
+	args := []any{word.TextValue, key, word.Difficulty, pq.Array(word.RelatedWords), word.Status, word.ID, word.Version}
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := w.DB.QueryRowContext(ctx, query, args...).Scan(&word.Version)
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	duplicate, err := approvedDuplicate(ctx, tx, key, word.ID)
+	if err != nil {
+		return err
+	}
+
+	if duplicate {
+		return ErrDuplicateWord
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&word.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return ErrRecordNotFound
+			return ErrEditConflict
+		case isDuplicateWord(err):
+			return ErrDuplicateWord
 		default:
 			return err
 		}
 	}
+
+	err = insertWordRevision(ctx, tx, word.ID, editorID, reason)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	w.Index.Update(word)
 	return nil
 }
 
-// Delete the word that matches the id and text
+// approvedDuplicate reports whether an approved word other than the one with the id has the
+// search key. The unique indexes only cover approved words among each other, so a draft
+// taking an approved word's key has to be caught by looking.
+func approvedDuplicate(ctx context.Context, tx *sql.Tx, key string, id int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM words WHERE search_value = $1 AND id <> $2 AND status = $3 AND deleted_at IS NULL)`
+
+	var duplicate bool
+	err := tx.QueryRowContext(ctx, query, key, id, StatusApproved).Scan(&duplicate)
+	return duplicate, err
+}
+
+// Delete moves the word to the trash. It stays there, hidden from Get and GetAll,
+// until it is restored or purged.
 func (w WordModel) Delete(id int64) error {
-	// this is probably an overkill, but I want to be sure that we are deleting the correct word
-	query := `DELETE FROM words WHERE id = $1`
+	query := `UPDATE words SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -146,33 +395,298 @@ func (w WordModel) Delete(id int64) error {
 		return ErrRecordNotFound
 	}
 
+	w.Index.Remove(id)
 	return nil
 }
 
-// GetAll
-func (w WordModel) GetAll(text string, difficulty string, filters Filters) ([]*Word, Metadata, error) {
-	// to_tsvector extracts the words out and disregards all the rest like punctuation and symbols.
-	// plainto_tsquery extracts words out from the search terms.
-	// @@ matches the left side and the right side.
-	query := fmt.Sprintf(`SELECT count(*) OVER(), id, text_value, difficulty, related_words, user_id, created_at, version 
-FROM words WHERE (to_tsvector('simple', text_value) @@ plainto_tsquery('simple', $1) OR $1 = '') AND (LOWER(difficulty) = LOWER($2) OR $2 = '')
-ORDER BY %s %s, id ASC LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDirection())
+// GetDeleted retrieves a word from the trash
+func (w WordModel) GetDeleted(id int64) (*Word, error) {
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+
+	query := `SELECT id, text_value, difficulty, related_words, user_id, status, created_at, deleted_at, version FROM words WHERE id = $1 AND deleted_at IS NOT NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{text, difficulty, filters.limit(), filters.offset()}
+	var word Word
+	err := w.DB.QueryRowContext(ctx, query, id).Scan(&word.ID, &word.TextValue, &word.Difficulty, pq.Array(&word.RelatedWords), &word.UserId, &word.Status, &word.CreatedAt, &word.DeletedAt, &word.Version)
 
-	rows, err := w.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, Metadata{}, err
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &word, nil
+}
+
+// Restore takes the word back out of the trash.
+// It returns ErrDuplicateWord if a word with the same search key was added while it was in the trash.
+func (w WordModel) Restore(word *Word) error {
+	query := `UPDATE words SET deleted_at = NULL WHERE id = $1 AND version = $2 AND deleted_at IS NOT NULL RETURNING version`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	duplicate, err := approvedDuplicate(ctx, tx, SearchKey(word.TextValue), word.ID)
+	if err != nil {
+		return err
+	}
+
+	if duplicate {
+		return ErrDuplicateWord
+	}
+
+	err = tx.QueryRowContext(ctx, query, word.ID, word.Version).Scan(&word.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		case isDuplicateWord(err):
+			return ErrDuplicateWord
+		default:
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	word.DeletedAt = nil
+	w.Index.Update(word)
+	return nil
+}
+
+// Purge permanently removes the words that were moved to the trash before the cutoff
+// and returns how many went.
+func (w WordModel) Purge(cutoff time.Time) (int64, error) {
+	query := `DELETE FROM words WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := w.DB.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// SearchKeyConflict is a word whose rebuilt search key is already held by other live words
+// it can't share it with. It is left as it was, for somebody to merge with those words.
+type SearchKeyConflict struct {
+	ID    int64   // the word left unchanged
+	Text  string  // its text
+	Key   string  // the key it should have
+	Holds []int64 // the words holding the key
+}
+
+// RebuildSearchKeys puts the text and search key of every word in the form NormalizeText
+// and SearchKey give, for rows written before they existed or by SQL that only approximates
+// them. It returns how many words changed, and the words it couldn't change because their
+// rebuilt key is taken: picking which of them to keep is left to a human, as any choice
+// made here could lose data.
+func (w WordModel) RebuildSearchKeys() (int, []SearchKeyConflict, error) {
+	query := `SELECT id, text_value, search_value, user_id, status FROM words WHERE id > $1 ORDER BY id LIMIT 1000`
+
+	var updated int
+	var conflicts []SearchKeyConflict
+	var last int64
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+
+		rows, err := w.DB.QueryContext(ctx, query, last)
+		if err != nil {
+			cancel()
+			return updated, conflicts, err
+		}
+
+		type row struct {
+			id     int64
+			text   string
+			key    string
+			userID int64
+			status string
+		}
+
+		var batch []row
+
+		for rows.Next() {
+			var r row
+
+			err := rows.Scan(&r.id, &r.text, &r.key, &r.userID, &r.status)
+			if err != nil {
+				rows.Close()
+				cancel()
+				return updated, conflicts, err
+			}
+
+			batch = append(batch, r)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		cancel()
+		if err != nil {
+			return updated, conflicts, err
+		}
+
+		if len(batch) == 0 {
+			return updated, conflicts, nil
+		}
+
+		for _, r := range batch {
+			text, key := NormalizeText(r.text), SearchKey(r.text)
+			if text == r.text && key == r.key {
+				continue
+			}
+
+			holds, err := w.rebuildSearchKey(r.id, text, key, r.userID, r.status)
+			if err != nil {
+				return updated, conflicts, err
+			}
+
+			if len(holds) > 0 {
+				conflicts = append(conflicts, SearchKeyConflict{ID: r.id, Text: r.text, Key: key, Holds: holds})
+				continue
+			}
+
+			updated++
+		}
+
+		last = batch[len(batch)-1].id
 	}
+}
+
+// rebuildSearchKey stores the text and search key of one word. If the key is taken it
+// leaves the word alone and returns the ids of the words holding the key.
+func (w WordModel) rebuildSearchKey(id int64, text, key string, userID int64, status string) ([]int64, error) {
+	query := `UPDATE words SET text_value = $1, search_value = $2 WHERE id = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
+	_, err := w.DB.ExecContext(ctx, query, text, key, id)
+	if err == nil || !isDuplicateWord(err) {
+		return nil, err
+	}
+
+	// The same rules as the unique indexes: one approved word per key, one live word per user
+	query = `
+		SELECT id FROM words
+		WHERE search_value = $1 AND id <> $2 AND deleted_at IS NULL
+		AND ((status = $3 AND $4 = $3) OR user_id = $5)
+		ORDER BY id`
+
+	rows, err := w.DB.QueryContext(ctx, query, key, id, StatusApproved, status, userID)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
-	var words []*Word
+	var holds []int64
+
+	for rows.Next() {
+		var holder int64
+
+		err := rows.Scan(&holder)
+		if err != nil {
+			return nil, err
+		}
+
+		holds = append(holds, holder)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A holder deleted in the meantime leaves nothing to report, so give up rather than
+	// count the word as updated; running the rebuild again picks it up
+	if len(holds) == 0 {
+		return nil, ErrDuplicateWord
+	}
+
+	return holds, nil
+}
+
+// Suggest returns up to limit approved word texts starting with prefix, ignoring case.
+// They come from the in-memory index once it has loaded, and from the database until then,
+// in the same byte order of their search keys either way.
+func (w WordModel) Suggest(prefix string, limit int) ([]string, error) {
+	if w.Index.Ready() {
+		return w.Index.Suggest(prefix, limit), nil
+	}
+
+	query := `
+		SELECT text_value FROM (
+			SELECT DISTINCT search_value, text_value FROM words
+			WHERE status = $1 AND deleted_at IS NULL AND search_value LIKE $2
+		) AS matches
+		ORDER BY search_value COLLATE "C", text_value COLLATE "C"
+		LIMIT $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := w.DB.QueryContext(ctx, query, StatusApproved, escapeLike(SearchKey(prefix))+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []string{}
 
+	for rows.Next() {
+		var text string
+
+		err := rows.Scan(&text)
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, text)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// GetAllDeleted lists the words in the trash
+func (w WordModel) GetAllDeleted(filters Filters) ([]*Word, Metadata, error) {
+	query := fmt.Sprintf(`SELECT count(*) OVER(), id, text_value, difficulty, related_words, user_id, status, created_at, deleted_at, version
+FROM words WHERE deleted_at IS NOT NULL
+ORDER BY %s %s, id ASC LIMIT $1 OFFSET $2`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := w.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	words := []*Word{}
 	totalRecords := 0
+
 	for rows.Next() {
 		var word Word
 		err := rows.Scan(
@@ -182,13 +696,14 @@ ORDER BY %s %s, id ASC LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDi
 			&word.Difficulty,
 			pq.Array(&word.RelatedWords),
 			&word.UserId,
+			&word.Status,
 			&word.CreatedAt,
+			&word.DeletedAt,
 			&word.Version)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 		words = append(words, &word)
-		totalRecords++
 	}
 
 	if err = rows.Err(); err != nil {
@@ -198,3 +713,180 @@ ORDER BY %s %s, id ASC LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortDi
 	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
 	return words, metadata, nil
 }
+
+// GetAll lists the words matching the search terms.
+// With filters.UseCursor set it returns a page of keyset pagination instead.
+func (w WordModel) GetAll(search WordSearch, filters Filters) ([]*Word, Metadata, error) {
+	where, score, args := search.where(nil)
+	column, direction := search.orderBy(filters, score)
+
+	keyset, args, err := filters.keysetCondition(args, column, direction)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if keyset != "" {
+		where += " AND " + keyset
+	}
+
+	// Keyset pages don't report a total, so they can skip counting every match
+	count := "count(*) OVER(), "
+	if filters.UseCursor {
+		count = ""
+	}
+
+	query := fmt.Sprintf(`SELECT %sid, text_value, difficulty, related_words, user_id, status, created_at, version, %s
+FROM words %s
+ORDER BY %s %s, id ASC LIMIT $%d OFFSET $%d`, count, score, where, column, direction, len(args)+1, len(args)+2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args = append(args, filters.limit(), filters.offset())
+
+	rows, err := w.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	var words []*Word
+
+	totalRecords := 0
+	for rows.Next() {
+		var word Word
+
+		dest := []any{
+			&word.ID,
+			&word.TextValue,
+			&word.Difficulty,
+			pq.Array(&word.RelatedWords),
+			&word.UserId,
+			&word.Status,
+			&word.CreatedAt,
+			&word.Version,
+			&word.Score,
+		}
+		if count != "" {
+			dest = append([]any{&totalRecords}, dest...)
+		}
+
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		if search.Text != "" {
+			word.Highlight = search.highlight(word.TextValue)
+		}
+
+		words = append(words, &word)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	if filters.UseCursor {
+		metadata := Metadata{PageSize: filters.PageSize}
+
+		// The extra row fetched by limit() means there is another page after this one
+		if len(words) > filters.PageSize {
+			words = words[:filters.PageSize]
+			last := words[len(words)-1]
+			metadata.NextCursor = encodeCursor(filters.Sort, last.sortValue(filters.sortColumn()), last.ID)
+		}
+
+		return words, metadata, nil
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return words, metadata, nil
+}
+
+// sortValue returns the value of the column the words are sorted by, for building a cursor
+func (w *Word) sortValue(column string) any {
+	switch column {
+	case "text_value":
+		return w.TextValue
+	case "difficulty":
+		return w.Difficulty
+	case "relevance":
+		return w.Score
+	default:
+		return nil
+	}
+}
+
+// Export streams every word matching the search to fn, in the order given by filters.Sort.
+// It reads through a server-side cursor inside a read-only transaction, so the export is a
+// consistent snapshot however long it takes, and only one batch is held in memory at a time.
+// Returning an error from fn stops the export.
+func (w WordModel) Export(search WordSearch, filters Filters, fn func(*Word) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	tx, err := w.DB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	where, score, args := search.where(nil)
+	column, direction := search.orderBy(filters, score)
+
+	query := fmt.Sprintf(`DECLARE words_export NO SCROLL CURSOR FOR
+SELECT id, text_value, difficulty, related_words, user_id, status, created_at, version
+FROM words %s
+ORDER BY %s %s, id ASC`, where, column, direction)
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.QueryContext(ctx, `FETCH 500 FROM words_export`)
+		if err != nil {
+			return err
+		}
+
+		fetched := 0
+
+		for rows.Next() {
+			var word Word
+
+			err := rows.Scan(
+				&word.ID,
+				&word.TextValue,
+				&word.Difficulty,
+				pq.Array(&word.RelatedWords),
+				&word.UserId,
+				&word.Status,
+				&word.CreatedAt,
+				&word.Version)
+			if err == nil {
+				err = fn(&word)
+			}
+
+			if err != nil {
+				rows.Close()
+				return err
+			}
+
+			fetched++
+		}
+
+		err = rows.Err()
+		rows.Close()
+
+		if err != nil {
+			return err
+		}
+
+		if fetched == 0 {
+			return nil
+		}
+	}
+}
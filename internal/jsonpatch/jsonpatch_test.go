@@ -0,0 +1,220 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		// add
+		{"add member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`},
+		{"add replaces existing member", `{"a":1}`, `[{"op":"add","path":"/a","value":2}]`, `{"a":2}`},
+		{"add nested member", `{"a":{"b":1}}`, `[{"op":"add","path":"/a/c","value":[1]}]`, `{"a":{"b":1,"c":[1]}}`},
+		{"add null member", `{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`},
+		{"add inserts into array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`},
+		{"add at array start", `{"a":[2]}`, `[{"op":"add","path":"/a/0","value":1}]`, `{"a":[1,2]}`},
+		{"add at array length", `{"a":[1]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2]}`},
+		{"add with dash appends", `{"a":[1,2]}`, `[{"op":"add","path":"/a/-","value":3}]`, `{"a":[1,2,3]}`},
+		{"add with dash to empty array", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":"x"}]`, `{"a":["x"]}`},
+		{"add replaces whole document", `{"a":1}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+
+		// remove
+		{"remove member", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`},
+		{"remove array element", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`},
+		{"remove last array element", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1]}`},
+
+		// replace
+		{"replace member", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`},
+		{"replace with null", `{"a":1}`, `[{"op":"replace","path":"/a","value":null}]`, `{"a":null}`},
+		{"replace array element", `{"a":[1,2]}`, `[{"op":"replace","path":"/a/0","value":0}]`, `{"a":[0,2]}`},
+		{"replace whole document", `{"a":1}`, `[{"op":"replace","path":"","value":{"b":2}}]`, `{"b":2}`},
+
+		// move
+		{"move member", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`},
+		{"move into object", `{"a":1,"b":{}}`, `[{"op":"move","from":"/a","path":"/b/a"}]`, `{"b":{"a":1}}`},
+		{"move array element", `{"a":[1,2,3]}`, `[{"op":"move","from":"/a/0","path":"/a/-"}]`, `{"a":[2,3,1]}`},
+		{"move onto itself", `{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`},
+
+		// copy
+		{"copy member", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`},
+		{"copy is deep", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"copy array element", `{"a":[1,2]}`, `[{"op":"copy","from":"/a/0","path":"/a/-"}]`, `{"a":[1,2,1]}`},
+
+		// test
+		{"test string", `{"a":"x"}`, `[{"op":"test","path":"/a","value":"x"}]`, `{"a":"x"}`},
+		{"test number", `{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`, `{"a":1}`},
+		{"test object", `{"a":{"b":[1,2]}}`, `[{"op":"test","path":"/a","value":{"b":[1,2]}}]`, `{"a":{"b":[1,2]}}`},
+		{"test array element", `{"a":[1,2]}`, `[{"op":"test","path":"/a/1","value":2}]`, `{"a":[1,2]}`},
+		{"test null", `{"a":null}`, `[{"op":"test","path":"/a","value":null}]`, `{"a":null}`},
+
+		// pointer escaping
+		{"tilde escape", `{"a~b":1}`, `[{"op":"replace","path":"/a~0b","value":2}]`, `{"a~b":2}`},
+		{"slash escape", `{"a/b":1}`, `[{"op":"replace","path":"/a~1b","value":2}]`, `{"a/b":2}`},
+		{"escapes are decoded once", `{"~1":1}`, `[{"op":"remove","path":"/~01"}]`, `{}`},
+		{"empty member name", `{"":1}`, `[{"op":"replace","path":"/","value":2}]`, `{"":2}`},
+		{"numeric member name", `{"0":1}`, `[{"op":"replace","path":"/0","value":2}]`, `{"0":2}`},
+		{"dash member name", `{"a":{}}`, `[{"op":"add","path":"/a/-","value":1}]`, `{"a":{"-":1}}`},
+
+		// several operations
+		{"operations run in order", `{"a":[]}`, `[{"op":"add","path":"/a/-","value":1},{"op":"test","path":"/a/0","value":1},{"op":"move","from":"/a","path":"/b"}]`, `{"b":[1]}`},
+		{"empty patch", `{"a":1}`, `[]`, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Apply([]byte(tt.doc), operations(t, tt.patch))
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			assertJSON(t, got, tt.want)
+		})
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+	}{
+		{"unknown op", `{}`, `[{"op":"merge","path":"/a","value":1}]`},
+		{"add without value", `{}`, `[{"op":"add","path":"/a"}]`},
+		{"add to missing parent", `{}`, `[{"op":"add","path":"/a/b","value":1}]`},
+		{"add past array end", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":1}]`},
+		{"add to scalar", `{"a":1}`, `[{"op":"add","path":"/a/b","value":1}]`},
+		{"remove missing member", `{}`, `[{"op":"remove","path":"/a"}]`},
+		{"remove whole document", `{}`, `[{"op":"remove","path":""}]`},
+		{"remove with dash", `{"a":[1]}`, `[{"op":"remove","path":"/a/-"}]`},
+		{"remove out of range", `{"a":[1]}`, `[{"op":"remove","path":"/a/1"}]`},
+		{"replace missing member", `{}`, `[{"op":"replace","path":"/a","value":1}]`},
+		{"replace with dash", `{"a":[1]}`, `[{"op":"replace","path":"/a/-","value":1}]`},
+		{"move from missing member", `{}`, `[{"op":"move","from":"/a","path":"/b"}]`},
+		{"move into own child", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`},
+		{"copy from missing member", `{}`, `[{"op":"copy","from":"/a","path":"/b"}]`},
+		{"test missing member", `{}`, `[{"op":"test","path":"/a","value":1}]`},
+		{"path without slash", `{"a":1}`, `[{"op":"remove","path":"a"}]`},
+		{"index with leading zero", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/01"}]`},
+		{"negative index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/-1"}]`},
+		{"signed index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/+1"}]`},
+		{"non-numeric index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/x"}]`},
+		{"failed operation undoes earlier ones", `{"a":1}`, `[{"op":"remove","path":"/a"},{"op":"remove","path":"/a"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), operations(t, tt.patch))
+			if err == nil {
+				t.Fatal("Apply() succeeded, want an error")
+			}
+
+			if errors.Is(err, ErrTestFailed) {
+				t.Errorf("Apply() error = %v, want an error other than ErrTestFailed", err)
+			}
+		})
+	}
+}
+
+func TestApplyTestFailed(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+	}{
+		{"different string", `{"a":"x"}`, `[{"op":"test","path":"/a","value":"y"}]`},
+		{"different type", `{"a":"1"}`, `[{"op":"test","path":"/a","value":1}]`},
+		{"null is not missing", `{"a":1}`, `[{"op":"test","path":"/a","value":null}]`},
+		{"array order matters", `{"a":[1,2]}`, `[{"op":"test","path":"/a","value":[2,1]}]`},
+		{"extra object member", `{"a":{"b":1}}`, `[{"op":"test","path":"/a","value":{"b":1,"c":2}}]`},
+		{"after an earlier change", `{"a":1}`, `[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":1}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Apply([]byte(tt.doc), operations(t, tt.patch))
+			if !errors.Is(err, ErrTestFailed) {
+				t.Errorf("Apply() error = %v, want ErrTestFailed", err)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesDocumentAlone(t *testing.T) {
+	doc := []byte(`{"a":[1,2]}`)
+
+	_, err := Apply(doc, operations(t, `[{"op":"remove","path":"/a/0"},{"op":"test","path":"/a","value":[]}]`))
+	if !errors.Is(err, ErrTestFailed) {
+		t.Fatalf("Apply() error = %v, want ErrTestFailed", err)
+	}
+
+	assertJSON(t, doc, `{"a":[1,2]}`)
+}
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    []string
+	}{
+		{"", []string{}},
+		{"/", []string{""}},
+		{"/a", []string{"a"}},
+		{"/a/0/b", []string{"a", "0", "b"}},
+		{"/a~1b", []string{"a/b"}},
+		{"/m~0n", []string{"m~n"}},
+		{"/~01", []string{"~1"}},
+		{"/~10", []string{"/0"}},
+		{"/ /-", []string{" ", "-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := parsePointer(tt.pointer)
+			if err != nil {
+				t.Fatalf("parsePointer(%q) error = %v", tt.pointer, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePointer(%q) = %q, want %q", tt.pointer, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parsePointer("a/b"); err == nil {
+		t.Error(`parsePointer("a/b") succeeded, want an error`)
+	}
+}
+
+func operations(t *testing.T, patch string) []Operation {
+	t.Helper()
+
+	var ops []Operation
+	if err := json.Unmarshal([]byte(patch), &ops); err != nil {
+		t.Fatalf("invalid patch %s: %v", patch, err)
+	}
+
+	return ops
+}
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid result %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid want %s: %v", want, err)
+	}
+
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package jwt
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Denylist remembers the sessions and users whose JWTs were revoked before they
+// expired, e.g. by signing out or deactivating an account, so those tokens can be
+// refused without a database lookup. An entry only has to outlive the tokens it
+// revokes, so it is dropped after ttl, the lifetime of an access token.
+//
+// The list lives in memory, so it only covers revocations made by this process;
+// the short access token lifetime bounds how long any other revocation takes.
+//
+// A nil *Denylist is valid and revokes nothing.
+type Denylist struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]time.Time     // session ID -> when the entry can go
+	users    map[string]denylistEntry // subject -> tokens issued up to revokedAt are refused
+	sweepAt  int
+}
+
+type denylistEntry struct {
+	revokedAt int64
+	expires   time.Time
+}
+
+// NewDenylist returns a denylist whose entries last for ttl
+func NewDenylist(ttl time.Duration) *Denylist {
+	return &Denylist{
+		ttl:      ttl,
+		sessions: make(map[string]time.Time),
+		users:    make(map[string]denylistEntry),
+		sweepAt:  1024,
+	}
+}
+
+// RevokeSession refuses every token issued from the login with the session ID
+func (d *Denylist) RevokeSession(sessionIDs ...string) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep()
+
+	expires := time.Now().Add(d.ttl)
+	for _, id := range sessionIDs {
+		if id != "" {
+			d.sessions[id] = expires
+		}
+	}
+}
+
+// RevokeUser refuses every token issued to the user so far
+func (d *Denylist) RevokeUser(userID int64) {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sweep()
+
+	now := time.Now()
+	d.users[strconv.FormatInt(userID, 10)] = denylistEntry{revokedAt: now.Unix(), expires: now.Add(d.ttl)}
+}
+
+// Revoked reports whether the token with the claims has been revoked
+func (d *Denylist) Revoked(claims *Claims) bool {
+	if d == nil {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	if expires, ok := d.sessions[claims.SessionID]; ok && now.Before(expires) {
+		return true
+	}
+
+	// iat only has second precision, so a token issued in the same second as the
+	// revocation is refused too
+	entry, ok := d.users[claims.Subject]
+	return ok && now.Before(entry.expires) && claims.IssuedAt <= entry.revokedAt
+}
+
+// sweep drops the expired entries once the list has grown, so it doesn't keep
+// every revocation ever made. The caller holds the lock.
+func (d *Denylist) sweep() {
+	if len(d.sessions)+len(d.users) < d.sweepAt {
+		return
+	}
+
+	now := time.Now()
+
+	for id, expires := range d.sessions {
+		if !now.Before(expires) {
+			delete(d.sessions, id)
+		}
+	}
+
+	for subject, entry := range d.users {
+		if !now.Before(entry.expires) {
+			delete(d.users, subject)
+		}
+	}
+
+	d.sweepAt = max(1024, 2*(len(d.sessions)+len(d.users)))
+}
@@ -0,0 +1,82 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDenylistRevokeSession(t *testing.T) {
+	d := NewDenylist(time.Minute)
+	now := time.Now().Unix()
+
+	revoked := &Claims{Subject: "1", IssuedAt: now, SessionID: "A"}
+	other := &Claims{Subject: "1", IssuedAt: now, SessionID: "B"}
+
+	if d.Revoked(revoked) {
+		t.Fatal("token revoked before anything was")
+	}
+
+	d.RevokeSession("A")
+
+	if !d.Revoked(revoked) {
+		t.Error("token of the revoked session accepted")
+	}
+	if d.Revoked(other) {
+		t.Error("token of another session of the user revoked")
+	}
+}
+
+func TestDenylistRevokeUser(t *testing.T) {
+	d := NewDenylist(time.Minute)
+	now := time.Now().Unix()
+
+	d.RevokeUser(1)
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		want   bool
+	}{
+		{"issued before", &Claims{Subject: "1", IssuedAt: now - 60, SessionID: "A"}, true},
+		{"issued in the same second", &Claims{Subject: "1", IssuedAt: now, SessionID: "A"}, true},
+		{"issued after", &Claims{Subject: "1", IssuedAt: now + 2, SessionID: "B"}, false},
+		{"another user", &Claims{Subject: "2", IssuedAt: now - 60, SessionID: "C"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.Revoked(tt.claims); got != tt.want {
+				t.Errorf("Revoked() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDenylistExpiry(t *testing.T) {
+	d := NewDenylist(20 * time.Millisecond)
+	claims := &Claims{Subject: "1", IssuedAt: time.Now().Unix(), SessionID: "A"}
+
+	d.RevokeSession("A")
+	d.RevokeUser(1)
+
+	if !d.Revoked(claims) {
+		t.Fatal("token accepted before the ttl")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if d.Revoked(claims) {
+		t.Error("token still revoked after the ttl")
+	}
+}
+
+func TestDenylistNil(t *testing.T) {
+	var d *Denylist
+
+	d.RevokeSession("A")
+	d.RevokeUser(1)
+
+	if d.Revoked(&Claims{Subject: "1", SessionID: "A"}) {
+		t.Error("nil denylist revoked a token")
+	}
+}
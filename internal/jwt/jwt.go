@@ -0,0 +1,277 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// The signing algorithms we support, named as in RFC 7518 and RFC 8037.
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+	ErrUnknownKey   = errors.New("token was signed with an unknown key")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Claims are the registered claims we use plus some user details for clients to show.
+// The server itself always loads the user from the database.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf"`
+	ExpiresAt int64  `json:"exp"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Activated bool   `json:"activated"`
+	SessionID string `json:"sid,omitempty"` // the login the token was issued from
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid"`
+}
+
+// Key is a named signing or verification key.
+// A key loaded from an Ed25519 public key can only verify tokens, which is
+// how retired keys are kept around until the tokens they signed expire.
+type Key struct {
+	ID        string
+	Algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+	public    ed25519.PublicKey
+}
+
+// LoadKey reads a key from a file. For HS256 the file holds the shared secret,
+// which must be at least 32 bytes long. For EdDSA it holds a PEM encoded
+// PKCS #8 Ed25519 private key, or a PKIX public key for verification only.
+func LoadKey(id, algorithm, path string) (*Key, error) {
+	if id == "" {
+		return nil, errors.New("jwt: key id must not be empty")
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id, Algorithm: algorithm}
+
+	switch algorithm {
+	case AlgHS256:
+		key.secret = []byte(strings.TrimSpace(string(contents)))
+		if len(key.secret) < 32 {
+			return nil, fmt.Errorf("jwt: HS256 secret for key %q must be at least 32 bytes", id)
+		}
+	case AlgEdDSA:
+		block, _ := pem.Decode(contents)
+		if block == nil {
+			return nil, fmt.Errorf("jwt: no PEM data found for key %q", id)
+		}
+
+		switch block.Type {
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			private, ok := parsed.(ed25519.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("jwt: key %q is not an Ed25519 private key", id)
+			}
+
+			key.private = private
+			key.public = private.Public().(ed25519.PublicKey)
+		case "PUBLIC KEY":
+			parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+
+			public, ok := parsed.(ed25519.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf("jwt: key %q is not an Ed25519 public key", id)
+			}
+
+			key.public = public
+		default:
+			return nil, fmt.Errorf("jwt: unsupported PEM block %q for key %q", block.Type, id)
+		}
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm %q", algorithm)
+	}
+
+	return key, nil
+}
+
+func (k *Key) canSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+func (k *Key) sign(signingInput string) []byte {
+	if k.Algorithm == AlgHS256 {
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(signingInput))
+		return mac.Sum(nil)
+	}
+
+	return ed25519.Sign(k.private, []byte(signingInput))
+}
+
+func (k *Key) verify(signingInput string, signature []byte) bool {
+	if k.Algorithm == AlgHS256 {
+		return hmac.Equal(signature, k.sign(signingInput))
+	}
+
+	return ed25519.Verify(k.public, []byte(signingInput), signature)
+}
+
+// Keyset signs tokens with one key and verifies them with any key it holds,
+// picking the key by the kid in the token header. Rotating keys means adding
+// the new key, making it the signing key, and dropping the old one once the
+// tokens it signed have expired.
+type Keyset struct {
+	issuer  string
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewKeyset returns a keyset that signs with the key named signingKeyID.
+func NewKeyset(issuer string, signingKeyID string, keys ...*Key) (*Keyset, error) {
+	ks := &Keyset{
+		issuer: issuer,
+		keys:   make(map[string]*Key, len(keys)),
+	}
+
+	for _, key := range keys {
+		if _, exists := ks.keys[key.ID]; exists {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	ks.signing = ks.keys[signingKeyID]
+	if ks.signing == nil {
+		return nil, fmt.Errorf("jwt: signing key %q not found", signingKeyID)
+	}
+
+	if !ks.signing.canSign() {
+		return nil, fmt.Errorf("jwt: signing key %q is a public key and cannot sign", signingKeyID)
+	}
+
+	return ks, nil
+}
+
+// Sign issues a token for the claims, valid for ttl from now.
+func (ks *Keyset) Sign(claims Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	claims.Issuer = ks.issuer
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+
+	headerJSON, err := json.Marshal(header{Algorithm: ks.signing.Algorithm, Type: "JWT", KeyID: ks.signing.ID})
+	if err != nil {
+		return "", err
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encoding.EncodeToString(headerJSON) + "." + encoding.EncodeToString(claimsJSON)
+	signature := ks.signing.sign(signingInput)
+
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
+
+// Verify checks the token's signature, issuer and validity period and returns its claims.
+func (ks *Keyset) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := json.Unmarshal(headerJSON, &h); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := ks.keys[h.KeyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	// The algorithm is fixed by the key, never by the token,
+	// otherwise a forged header could downgrade the check.
+	if h.Algorithm != key.Algorithm {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !key.verify(parts[0]+"."+parts[1], signature) {
+		return nil, ErrInvalidToken
+	}
+
+	claimsJSON, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Issuer != ks.issuer {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now().Unix()
+
+	if now < claims.NotBefore {
+		return nil, ErrInvalidToken
+	}
+
+	if now >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+// LooksLikeJWT reports whether a bearer token has the three dot separated parts of a JWT,
+// as opposed to the opaque tokens stored in the database.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
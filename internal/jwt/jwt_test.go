@@ -0,0 +1,267 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testIssuer = "kite-api-test"
+
+// writeFile writes contents to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name string, contents []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, contents, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func newHS256Key(t *testing.T, id string) *Key {
+	t.Helper()
+
+	key, err := LoadKey(id, AlgHS256, writeFile(t, id, []byte(strings.Repeat(id, 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+// newEdDSAKeys returns a signing key and a verify-only key made from its public half
+func newEdDSAKeys(t *testing.T, id string) (*Key, *Key) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+
+	signing, err := LoadKey(id, AlgEdDSA, writeFile(t, id+".pem", privatePEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifying, err := LoadKey(id, AlgEdDSA, writeFile(t, id+".pub.pem", publicPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signing, verifying
+}
+
+func newKeyset(t *testing.T, signingKeyID string, keys ...*Key) *Keyset {
+	t.Helper()
+
+	ks, err := NewKeyset(testIssuer, signingKeyID, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ks
+}
+
+// forge builds a token from a raw header and claims, signed with key
+func forge(t *testing.T, key *Key, h header, claims Claims) string {
+	t.Helper()
+
+	headerJSON, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := encoding.EncodeToString(headerJSON) + "." + encoding.EncodeToString(claimsJSON)
+	return signingInput + "." + encoding.EncodeToString(key.sign(signingInput))
+}
+
+func TestSignVerify(t *testing.T) {
+	hs := newHS256Key(t, "hs")
+	ed, edPublic := newEdDSAKeys(t, "ed")
+
+	tests := []struct {
+		name   string
+		signer *Keyset
+		verify *Keyset
+	}{
+		{"HS256", newKeyset(t, "hs", hs), newKeyset(t, "hs", hs)},
+		{"EdDSA", newKeyset(t, "ed", ed), newKeyset(t, "ed", ed)},
+		{"EdDSA verified with the public key only", newKeyset(t, "ed", ed), newKeyset(t, "hs", hs, edPublic)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := tt.signer.Sign(Claims{Subject: "42", Email: "kite@example.com", SessionID: "family"}, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !LooksLikeJWT(token) {
+				t.Fatalf("LooksLikeJWT(%q) = false", token)
+			}
+
+			claims, err := tt.verify.Verify(token)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if claims.Subject != "42" || claims.Email != "kite@example.com" || claims.SessionID != "family" || claims.Issuer != testIssuer {
+				t.Errorf("Verify() claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	hs := newHS256Key(t, "hs")
+	other := newHS256Key(t, "other")
+	ed, _ := newEdDSAKeys(t, "ed")
+
+	ks := newKeyset(t, "hs", hs, ed)
+
+	valid, err := ks.Sign(Claims{Subject: "42"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(valid, ".")
+	now := time.Now()
+
+	claims := func(issuer string, nbf, exp time.Time) Claims {
+		return Claims{Subject: "42", Issuer: issuer, IssuedAt: now.Unix(), NotBefore: nbf.Unix(), ExpiresAt: exp.Unix()}
+	}
+
+	// Flip the last character of the signature
+	last := parts[2][len(parts[2])-1:]
+	flipped := "A"
+	if last == "A" {
+		flipped = "B"
+	}
+	tamperedSignature := parts[0] + "." + parts[1] + "." + parts[2][:len(parts[2])-1] + flipped
+
+	// Swap in claims for somebody else, keeping the original signature
+	otherClaims, _ := json.Marshal(claims(testIssuer, now, now.Add(time.Minute)))
+	forgedSubject := strings.Replace(string(otherClaims), `"sub":"42"`, `"sub":"1"`, 1)
+	tamperedClaims := parts[0] + "." + encoding.EncodeToString([]byte(forgedSubject)) + "." + parts[2]
+
+	// Without the secret an attacker can still sign with an empty key
+	noneKey := &Key{ID: "hs", Algorithm: AlgHS256, secret: []byte{}}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"not a JWT", "abc", ErrInvalidToken},
+		{"bad header encoding", "!!!." + parts[1] + "." + parts[2], ErrInvalidToken},
+		{"tampered signature", tamperedSignature, ErrInvalidToken},
+		{"tampered claims", tamperedClaims, ErrInvalidToken},
+		{"unknown kid", forge(t, other, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "other"}, claims(testIssuer, now, now.Add(time.Minute))), ErrUnknownKey},
+		{"signed with another secret under a known kid", forge(t, other, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "hs"}, claims(testIssuer, now, now.Add(time.Minute))), ErrInvalidToken},
+		{"signed with an empty secret", forge(t, noneKey, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "hs"}, claims(testIssuer, now, now.Add(time.Minute))), ErrInvalidToken},
+		{"algorithm does not match the key", forge(t, hs, header{Algorithm: AlgEdDSA, Type: "JWT", KeyID: "hs"}, claims(testIssuer, now, now.Add(time.Minute))), ErrInvalidToken},
+		{"algorithm none", forge(t, hs, header{Algorithm: "none", Type: "JWT", KeyID: "hs"}, claims(testIssuer, now, now.Add(time.Minute))), ErrInvalidToken},
+		{"HS256 header on an EdDSA key", forge(t, &Key{ID: "ed", Algorithm: AlgHS256, secret: ed.public}, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "ed"}, claims(testIssuer, now, now.Add(time.Minute))), ErrInvalidToken},
+		{"wrong issuer", forge(t, hs, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "hs"}, claims("somebody-else", now, now.Add(time.Minute))), ErrInvalidToken},
+		{"not valid yet", forge(t, hs, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "hs"}, claims(testIssuer, now.Add(time.Hour), now.Add(2*time.Hour))), ErrInvalidToken},
+		{"expired", forge(t, hs, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "hs"}, claims(testIssuer, now.Add(-time.Hour), now.Add(-time.Minute))), ErrExpiredToken},
+		{"expires now", forge(t, hs, header{Algorithm: AlgHS256, Type: "JWT", KeyID: "hs"}, claims(testIssuer, now.Add(-time.Hour), now)), ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ks.Verify(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+
+			if claims != nil {
+				t.Errorf("Verify() claims = %+v, want nil", claims)
+			}
+		})
+	}
+}
+
+func TestSignExpired(t *testing.T) {
+	ks := newKeyset(t, "hs", newHS256Key(t, "hs"))
+
+	token, err := ks.Sign(Claims{Subject: "42"}, -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ks.Verify(token); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrExpiredToken)
+	}
+}
+
+func TestNewKeyset(t *testing.T) {
+	hs := newHS256Key(t, "hs")
+	_, edPublic := newEdDSAKeys(t, "ed")
+
+	tests := []struct {
+		name         string
+		signingKeyID string
+		keys         []*Key
+	}{
+		{"missing signing key", "nope", []*Key{hs}},
+		{"public key cannot sign", "ed", []*Key{hs, edPublic}},
+		{"duplicate key id", "hs", []*Key{hs, hs}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyset(testIssuer, tt.signingKeyID, tt.keys...); err == nil {
+				t.Error("NewKeyset() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		contents  string
+	}{
+		{"short HS256 secret", AlgHS256, "too short"},
+		{"EdDSA key that isn't PEM", AlgEdDSA, "not pem"},
+		{"unsupported algorithm", "RS256", strings.Repeat("x", 32)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKey("key", tt.algorithm, writeFile(t, "key", []byte(tt.contents))); err == nil {
+				t.Error("LoadKey() error = nil, want an error")
+			}
+		})
+	}
+}
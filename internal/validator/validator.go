@@ -5,6 +5,8 @@ import "regexp"
 var (
 	EmailRX  = regexp.MustCompile("^[a-zA-Z0-9._-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+$")
 	UserIDRX = regexp.MustCompile("^ID-[0-9]{1,32}$")
+	// LanguageRX matches BCP 47 style language tags such as "en", "ksw" or "en-AU".
+	LanguageRX = regexp.MustCompile("^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$")
 )
 
 type Validator struct {
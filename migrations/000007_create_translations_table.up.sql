@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS translations (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    word_id bigint NOT NULL REFERENCES words ON DELETE CASCADE,
+    language text NOT NULL,
+    text_value text NOT NULL,
+    notes text NOT NULL DEFAULT '',
+    author_id bigint NOT NULL,
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS translations_word_id_idx ON translations (word_id);
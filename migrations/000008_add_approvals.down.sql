@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS approvals;
+DROP INDEX IF EXISTS words_status_idx;
+ALTER TABLE translations DROP COLUMN IF EXISTS status;
+ALTER TABLE words DROP COLUMN IF EXISTS status;
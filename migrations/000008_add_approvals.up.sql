@@ -0,0 +1,26 @@
+ALTER TABLE words ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'draft';
+ALTER TABLE translations ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'draft';
+
+-- Everything published before the approval workflow existed stays visible to learners.
+UPDATE words SET status = 'approved';
+UPDATE translations SET status = 'approved';
+
+ALTER TABLE words ADD CONSTRAINT words_status_check CHECK (status IN ('draft', 'submitted', 'approved', 'rejected'));
+ALTER TABLE translations ADD CONSTRAINT translations_status_check CHECK (status IN ('draft', 'submitted', 'approved', 'rejected'));
+
+CREATE INDEX IF NOT EXISTS words_status_idx ON words (status);
+
+CREATE TABLE IF NOT EXISTS approvals (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    word_id bigint REFERENCES words ON DELETE CASCADE,
+    translation_id bigint REFERENCES translations ON DELETE CASCADE,
+    actor_id bigint NOT NULL,
+    from_status text NOT NULL,
+    to_status text NOT NULL,
+    comment text NOT NULL DEFAULT '',
+    CONSTRAINT approvals_target_check CHECK ((word_id IS NULL) <> (translation_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS approvals_word_id_idx ON approvals (word_id);
+CREATE INDEX IF NOT EXISTS approvals_translation_id_idx ON approvals (translation_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS feedbacks (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    word_id bigint NOT NULL REFERENCES words ON DELETE CASCADE,
+    user_id bigint NOT NULL,
+    category text NOT NULL,
+    message text NOT NULL,
+    status text NOT NULL DEFAULT 'open',
+    version integer NOT NULL DEFAULT 1,
+    CONSTRAINT feedbacks_category_check CHECK (category IN ('wrong_translation', 'typo', 'offensive', 'other')),
+    CONSTRAINT feedbacks_status_check CHECK (status IN ('open', 'acknowledged', 'resolved'))
+);
+
+CREATE INDEX IF NOT EXISTS feedbacks_word_id_idx ON feedbacks (word_id);
+CREATE INDEX IF NOT EXISTS feedbacks_status_idx ON feedbacks (status, created_at);
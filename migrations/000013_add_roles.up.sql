@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id bigserial PRIMARY KEY,
+    code text UNIQUE NOT NULL,
+    description text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission_id bigint NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS users_roles (
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    role_id bigint NOT NULL REFERENCES roles ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+
+INSERT INTO roles (code, description)
+VALUES
+('learner', 'Reads the dictionary and reports problems with it'),
+('contributor', 'Adds and edits words and translations'),
+('reviewer', 'Approves contributions and triages learner feedback'),
+('admin', 'Manages users, roles and permissions');
+
+INSERT INTO roles_permissions
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE (roles.code = 'learner' AND permissions.code IN ('words:read', 'translations:read', 'feedbacks:write'))
+OR (roles.code = 'contributor' AND permissions.code IN ('words:read', 'words:write', 'translations:read', 'translations:write', 'feedbacks:write'))
+OR (roles.code = 'reviewer' AND permissions.code IN ('words:read', 'words:write', 'translations:read', 'translations:write',
+                                                      'approvals:read', 'approvals:write', 'feedbacks:read', 'feedbacks:write'))
+OR (roles.code = 'admin');
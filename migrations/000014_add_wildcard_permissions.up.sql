@@ -0,0 +1,3 @@
+INSERT INTO permissions (code)
+SELECT code FROM (VALUES ('*'), ('*:read'), ('words:*'), ('translations:*')) AS wildcards(code)
+WHERE NOT EXISTS (SELECT 1 FROM permissions WHERE permissions.code = wildcards.code);
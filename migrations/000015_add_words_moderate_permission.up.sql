@@ -0,0 +1,9 @@
+INSERT INTO permissions (code)
+SELECT 'words:moderate'
+WHERE NOT EXISTS (SELECT 1 FROM permissions WHERE code = 'words:moderate');
+
+INSERT INTO roles_permissions
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.code IN ('reviewer', 'admin') AND permissions.code = 'words:moderate'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS words_deleted_at_idx;
+
+ALTER TABLE words DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE words ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS words_deleted_at_idx ON words (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS word_revisions (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    word_id bigint NOT NULL REFERENCES words ON DELETE CASCADE,
+    version integer NOT NULL,
+    text_value text NOT NULL,
+    difficulty text NOT NULL,
+    related_words text[] NOT NULL,
+    status text NOT NULL,
+    editor_id bigint NOT NULL,
+    reason text NOT NULL DEFAULT '',
+    UNIQUE (word_id, version)
+);
+
+-- Existing words start their history at the version they are at now
+INSERT INTO word_revisions (created_at, word_id, version, text_value, difficulty, related_words, status, editor_id, reason)
+SELECT created_at, id, version, text_value, difficulty, related_words, status, user_id, ''
+FROM words;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS words_related_words_idx;
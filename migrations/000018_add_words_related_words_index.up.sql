@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS words_related_words_idx ON words USING GIN (related_words);
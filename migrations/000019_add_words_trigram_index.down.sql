@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS words_text_value_trgm_idx;
+
+DROP EXTENSION IF EXISTS pg_trgm;
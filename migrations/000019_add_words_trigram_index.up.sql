@@ -0,0 +1,4 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Serves both the similarity operator (%) and the ILIKE used for prefix searches
+CREATE INDEX IF NOT EXISTS words_text_value_trgm_idx ON words USING GIN (text_value gin_trgm_ops);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS words_search_value_trgm_idx;
+DROP INDEX IF EXISTS words_search_value_tsv_idx;
+DROP INDEX IF EXISTS words_search_value_idx;
+
+ALTER TABLE words DROP COLUMN IF EXISTS search_value;
+
+CREATE INDEX IF NOT EXISTS words_text_idx ON words USING GIN (to_tsvector('simple', text_value));
+CREATE INDEX IF NOT EXISTS words_text_value_trgm_idx ON words USING GIN (text_value gin_trgm_ops);
@@ -0,0 +1,20 @@
+-- Store text in NFC so the same word can't be saved in several Unicode forms
+UPDATE words SET text_value = btrim(normalize(text_value, NFC));
+UPDATE translations SET text_value = btrim(normalize(text_value, NFC));
+
+-- search_value is the key words are searched and de-duplicated by, kept up to date by the API.
+-- This backfill only approximates it: lower() instead of full case folding, zero-width characters
+-- removed and whitespace collapsed. Run cmd/searchkeys afterwards to rebuild the keys exactly.
+ALTER TABLE words ADD COLUMN IF NOT EXISTS search_value text NOT NULL DEFAULT '';
+
+UPDATE words SET search_value = btrim(regexp_replace(
+    lower(regexp_replace(text_value, '[\u200B-\u200D\u2060\uFEFF]', '', 'g')),
+    '\s+', ' ', 'g'));
+
+-- Searches now go through search_value, so its indexes replace the ones on text_value
+DROP INDEX IF EXISTS words_text_idx;
+DROP INDEX IF EXISTS words_text_value_trgm_idx;
+
+CREATE INDEX IF NOT EXISTS words_search_value_idx ON words (search_value text_pattern_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS words_search_value_tsv_idx ON words USING GIN (to_tsvector('simple', search_value));
+CREATE INDEX IF NOT EXISTS words_search_value_trgm_idx ON words USING GIN (search_value gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS tokens_id_idx;
+
+ALTER TABLE tokens DROP COLUMN IF EXISTS id;
@@ -0,0 +1,4 @@
+-- Sessions are listed to their users, who need a way to point at one to sign it out
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS id bigserial;
+
+CREATE UNIQUE INDEX IF NOT EXISTS tokens_id_idx ON tokens (id);
@@ -0,0 +1,3 @@
+-- Disabling is separate from activation, so an administrator's decision
+-- can't be undone by activating the account again
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at timestamp(0) with time zone;
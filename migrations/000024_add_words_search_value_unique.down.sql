@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS words_user_id_search_value_key;
+DROP INDEX IF EXISTS words_search_value_approved_key;
@@ -0,0 +1,28 @@
+-- Duplicates are kept out by unique indexes rather than checks made before writing, which
+-- two requests could both pass. A search key can belong to only one approved word, and each
+-- user can hold only one live word with it, so a draft never clashes with another user's draft.
+-- Existing duplicates are not removed here, because picking which word to keep can lose data:
+-- the migration fails and lists them, so they can be merged by hand before it is run again.
+DO $$
+DECLARE
+    conflicts text;
+BEGIN
+    SELECT string_agg(format('%s (words %s)', search_value, ids), '; ') INTO conflicts
+    FROM (
+        SELECT search_value, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM words WHERE deleted_at IS NULL AND status = 'approved'
+        GROUP BY search_value HAVING count(*) > 1
+        UNION ALL
+        SELECT search_value, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM words WHERE deleted_at IS NULL
+        GROUP BY user_id, search_value HAVING count(*) > 1
+    ) AS duplicates;
+
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'words share a search key, merge or delete them first: %', conflicts;
+    END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS words_search_value_approved_key ON words (search_value) WHERE deleted_at IS NULL AND status = 'approved';
+CREATE UNIQUE INDEX IF NOT EXISTS words_user_id_search_value_key ON words (user_id, search_value) WHERE deleted_at IS NULL;
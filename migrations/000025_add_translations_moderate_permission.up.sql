@@ -0,0 +1,9 @@
+INSERT INTO permissions (code)
+SELECT 'translations:moderate'
+WHERE NOT EXISTS (SELECT 1 FROM permissions WHERE code = 'translations:moderate');
+
+INSERT INTO roles_permissions
+SELECT roles.id, permissions.id
+FROM roles, permissions
+WHERE roles.code IN ('reviewer', 'admin') AND permissions.code = 'translations:moderate'
+ON CONFLICT DO NOTHING;
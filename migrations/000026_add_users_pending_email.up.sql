@@ -0,0 +1,3 @@
+-- A changed email address waits here until it is confirmed, so the account
+-- keeps its working address if the new one is mistyped or not the owner's
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email citext;
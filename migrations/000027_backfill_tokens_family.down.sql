@@ -0,0 +1 @@
+UPDATE tokens SET family = NULL WHERE family LIKE 'legacy-%';
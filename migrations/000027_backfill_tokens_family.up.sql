@@ -0,0 +1,2 @@
+-- Sessions are listed and signed out by family, so logins from before families existed get one each.
+UPDATE tokens SET family = 'legacy-' || id WHERE family IS NULL AND scope IN ('authentication', 'refresh');